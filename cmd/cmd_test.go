@@ -0,0 +1,392 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/r0busta/go-shopify-reports/mtd"
+	"github.com/r0busta/go-shopify-reports/mtd/mtdtest"
+	"github.com/r0busta/go-shopify-reports/report"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/shop/shoptest"
+)
+
+// newStore starts a fake store with the fixture orders, points the Shopify client at it
+// and runs the test in a temporary directory, where the cache and local database are written.
+func newStore(t *testing.T, fixture []byte) *shoptest.Server {
+	srv, err := shoptest.NewServer(fixture)
+	if err != nil {
+		t.Fatalf("NewServer(), err=%v", err)
+	}
+	t.Cleanup(srv.Close)
+
+	t.Setenv("STORE_API_URL", srv.GraphQLURL())
+	t.Setenv("STORE_NAME", "test-store")
+	t.Setenv("STORE_TIMEZONE", "")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd(), err=%v", err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Chdir(), err=%v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	return srv
+}
+
+// run runs the command line and returns what it wrote to stdout.
+func run(t *testing.T, args ...string) (string, error) {
+	cli := CLI{}
+	parser, err := kong.New(&cli,
+		kong.Name("vat"),
+		kong.BindTo(context.Background(), (*context.Context)(nil)),
+		kong.Exit(func(int) { t.Fatalf("%s exited", strings.Join(args, " ")) }))
+	if err != nil {
+		t.Fatalf("kong.New(), err=%v", err)
+	}
+	ctx, err := parser.Parse(args)
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	stdout = &out
+	defer func() { stdout = os.Stdout }()
+
+	err = ctx.Run(&cli.Globals)
+	return out.String(), err
+}
+
+func TestReportCommands(t *testing.T) {
+	newStore(t, shoptest.Orders)
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			[]string{"corporate-tax", "2020-04"},
+			[]string{"Total turnover (excl. VAT): 140.00", "Total tax (VAT): 18.00"},
+		},
+		{
+			[]string{"corporate-tax", "--basis=accrual", "--compare=previous", "--format=csv", "2020-04"},
+			[]string{"Total turnover (excl. VAT),140.00,90.00,50.00,55.6%"},
+		},
+		{
+			[]string{"vat", "flat", "--flat-rate=7.5", "--timezone=UTC", "2020-04"},
+			[]string{"VAT due on sales and other outputs (box 1): 11.85", "Total turnover, including VAT and EC sales (box 6): 158.00"},
+		},
+		{
+			[]string{"vat", "standard", "--format=json", "2020-04"},
+			[]string{`"name": "Net VAT to pay to HMRC or reclaim (box 5)"`, `"value": "140"`},
+		},
+		{
+			[]string{"vat", "oss", "--eur-rate=1.1", "--format=csv", "2020-04"},
+			[]string{"DE,", "Total OSS VAT payable (EUR)"},
+		},
+		{
+			[]string{"vendor", "--format=csv", "2020-04"},
+			[]string{"Acme,2,2,90.00,(12.00),13.33%", "Basics Co,4,5,116.00,(36.00),31.03%"},
+		},
+		{
+			[]string{"tag", "--tags=denim", "--format=csv", "2020-04"},
+			[]string{"denim,2,2,45.00,(6.00),13.33%"},
+		},
+		{
+			[]string{"product", "--variants", "--format=csv", "2020-04"},
+			[]string{"Plain tee,Default Title,TEE-1,4,4,1,116.00,(36.00),80.00", "Slim jeans,Default Title,JEANS-32,1,1,0,90.00,(12.00),78.00"},
+		},
+		{
+			[]string{"sales", "--by=country", "--metrics=orders,net", "--format=csv", "2020-04"},
+			[]string{"DE,1,50.00", "GB,4,108.00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			out, err := run(t, tt.args...)
+			if err != nil {
+				t.Fatalf("run(), err=%v", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("output doesn't contain %q:\n%s", w, out)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncCommand(t *testing.T) {
+	srv := newStore(t, shoptest.Orders)
+
+	out, err := run(t, "sync")
+	if err != nil {
+		t.Fatalf("sync, err=%v", err)
+	}
+	if want := "Synced 6 orders, up to date as of Apr 28, 2020 11:00:00"; !strings.Contains(out, want) {
+		t.Errorf("sync output = %q, want %q", out, want)
+	}
+
+	out, err = run(t, "sync")
+	if err != nil {
+		t.Fatalf("sync again, err=%v", err)
+	}
+	if want := "Synced 1 orders"; !strings.Contains(out, want) {
+		t.Errorf("sync again output = %q, want %q", out, want)
+	}
+	if got := srv.Filters()[1]; got != "updated_at:>='2020-04-28T11:00:00Z'" {
+		t.Errorf("sync again bulk query filter = %q", got)
+	}
+
+	out, err = run(t, "--offline", "corporate-tax", "2020-04")
+	if err != nil {
+		t.Fatalf("offline corporate-tax, err=%v", err)
+	}
+	if want := "Total turnover (excl. VAT): 140.00"; !strings.Contains(out, want) {
+		t.Errorf("offline corporate-tax output doesn't contain %q:\n%s", want, out)
+	}
+}
+
+func TestVATSubmitCommand(t *testing.T) {
+	newStore(t, shoptest.Orders)
+
+	const vrn = "123456789"
+	hmrc := mtdtest.NewServer(vrn, []mtd.Obligation{
+		{Start: "2020-04-01", End: "2020-06-30", Due: "2020-08-07", Status: "O", PeriodKey: "20A2"},
+	})
+	defer hmrc.Close()
+
+	args := []string{"vat", "submit", "flat", "--flat-rate=7.5", "--vrn=" + vrn, "--mtd-url=" + hmrc.URL, "2020-Q2"}
+	_, err := run(t, args...)
+	if !errors.Is(err, mtd.ErrNotAuthorized) {
+		t.Fatalf("submit before authorizing, err=%v, want %v", err, mtd.ErrNotAuthorized)
+	}
+
+	out, err := run(t, append(args, "--auth-code="+mtdtest.AuthCode)...)
+	if err != nil {
+		t.Fatalf("submit draft, err=%v", err)
+	}
+	if want := "not submitted (see --finalise)"; !strings.Contains(out, want) {
+		t.Errorf("submit draft output doesn't contain %q:\n%s", want, out)
+	}
+
+	out, err = run(t, append(args, "--finalise")...)
+	if err != nil {
+		t.Fatalf("submit, err=%v", err)
+	}
+	if want := "Submitted return for the period key 20A2"; !strings.Contains(out, want) {
+		t.Errorf("submit output doesn't contain %q:\n%s", want, out)
+	}
+	submissions := hmrc.Submissions()
+	if len(submissions) != 1 {
+		t.Fatalf("submitted %d returns, want 1", len(submissions))
+	}
+	if got := submissions[0].VATDueSales.String(); got != "11.85" {
+		t.Errorf("submitted box 1 = %s, want 11.85", got)
+	}
+}
+
+func TestReportCommandError(t *testing.T) {
+	srv := newStore(t, shoptest.Orders)
+	srv.Close()
+
+	_, err := run(t, "corporate-tax", "--timezone=UTC", "2020-04")
+	if err == nil || !strings.Contains(err.Error(), "error getting orders") {
+		t.Errorf("corporate-tax with the store down, err=%v, want error getting orders", err)
+	}
+}
+
+// newStores starts a fake store for each profile with the fixture orders, and writes the stores file
+// in a temporary directory the test runs in.
+func newStores(t *testing.T, profiles []shop.Store, fixtures [][]byte) {
+	for i := range profiles {
+		srv, err := shoptest.NewServer(fixtures[i])
+		if err != nil {
+			t.Fatalf("NewServer(), err=%v", err)
+		}
+		t.Cleanup(srv.Close)
+		profiles[i].APIURL = srv.GraphQLURL()
+	}
+	t.Setenv("STORE_TIMEZONE", "")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd(), err=%v", err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Chdir(), err=%v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	b, err := json.Marshal(map[string]interface{}{"stores": profiles})
+	if err != nil {
+		t.Fatalf("Marshal(), err=%v", err)
+	}
+	err = os.WriteFile("stores.json", b, 0600)
+	if err != nil {
+		t.Fatalf("WriteFile(), err=%v", err)
+	}
+}
+
+func TestStoreProfiles(t *testing.T) {
+	outlet, err := os.ReadFile(filepath.Join("testdata", "fixtures", "q2-2020.jsonl"))
+	if err != nil {
+		t.Fatalf("ReadFile(), err=%v", err)
+	}
+	newStores(t, []shop.Store{
+		{Name: "main", Currency: "GBP", Timezone: "Europe/London", VATNumber: "123456789"},
+		{Name: "outlet", Currency: "GBP", Timezone: "Europe/London", VATNumber: "123456789"},
+	}, [][]byte{shoptest.Orders, outlet})
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			[]string{"--store=main", "vendor", "--format=csv", "2020-04"},
+			[]string{"Vendor,Orders Count,", "Acme,2,2,90.00,(12.00),13.33%"},
+		},
+		{
+			[]string{"--store=all", "vendor", "--format=csv", "2020-04"},
+			[]string{
+				"Store,Vendor,Orders Count,",
+				"main,Acme,2,2,90.00,(12.00),13.33%",
+				"main,Subtotal,5,7,206.00,(48.00),23.30%",
+				"outlet,Subtotal,5,11,345.00,(40.00),11.59%",
+				"All stores,Acme,4,5,180.00,(52.00),28.89%",
+				"All stores,Total,10,18,551.00,(88.00),15.97%",
+			},
+		},
+		{
+			[]string{"--store=all", "tag", "--tags=denim", "--compare=previous", "--format=csv", "2020-05"},
+			[]string{"main,denim,0,2,-2,-100.0%", "All stores,denim,2,4,-2,-50.0%", "All stores,Total,2,4,-2,-50.0%"},
+		},
+		{
+			[]string{"--store=all", "corporate-tax", "--format=csv", "2020-04"},
+			[]string{"main,140.00,18.00", "outlet,274.17,30.83", "Total turnover (excl. VAT),414.17"},
+		},
+		{
+			[]string{"--store=all", "corporate-tax", "--compare=previous", "--format=csv", "2020-05"},
+			[]string{"main,Total turnover (excl. VAT),0.00,140.00", "All stores,Total turnover (excl. VAT),150.00,414.17,-264.17,-63.8%"},
+		},
+		{
+			[]string{"--store=all", "vat", "flat", "--flat-rate=7.5", "--format=csv", "2020-04"},
+			[]string{"VAT due on sales and other outputs (box 1),34.73", "main,1,VAT due on sales and other outputs,11.85", "outlet,3,Total VAT due,22.88"},
+		},
+		{
+			[]string{"--store=all", "vat", "standard", "--compare=previous", "--format=csv", "2020-05"},
+			[]string{"outlet,1,VAT due on sales and other outputs,11.00,30.83,-19.83,-64.3%", "All stores,1,VAT due on sales and other outputs,11.00,48.83"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			out, err := run(t, tt.args...)
+			if err != nil {
+				t.Fatalf("run(), err=%v", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("output doesn't contain %q:\n%s", w, out)
+				}
+			}
+		})
+	}
+
+	_, err = run(t, "--store=uk", "vendor", "2020-04")
+	if err == nil || !strings.Contains(err.Error(), "unknown store `uk`, expected one of main, outlet or all") {
+		t.Errorf("unknown store, err=%v", err)
+	}
+}
+
+func TestStoreProfilesSync(t *testing.T) {
+	outlet, err := os.ReadFile(filepath.Join("testdata", "fixtures", "q2-2020.jsonl"))
+	if err != nil {
+		t.Fatalf("ReadFile(), err=%v", err)
+	}
+	newStores(t, []shop.Store{
+		{Name: "main", VATNumber: "123456789"},
+		{Name: "outlet", VATNumber: "987654321"},
+	}, [][]byte{shoptest.Orders, outlet})
+
+	out, err := run(t, "--store=all", "sync")
+	if err != nil {
+		t.Fatalf("sync, err=%v", err)
+	}
+	for _, want := range []string{"main: Synced 6 orders", "outlet: Synced 10 orders"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("sync output doesn't contain %q:\n%s", want, out)
+		}
+	}
+	for _, path := range []string{"_orders-main.db", "_orders-outlet.db"} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("sync didn't write %s, err=%v", path, err)
+		}
+	}
+
+	out, err = run(t, "--offline", "--store=all", "corporate-tax", "--format=csv", "2020-04")
+	if err != nil {
+		t.Fatalf("offline corporate-tax, err=%v", err)
+	}
+	if want := "Total turnover (excl. VAT),414.17"; !strings.Contains(out, want) {
+		t.Errorf("offline corporate-tax output doesn't contain %q:\n%s", want, out)
+	}
+
+	_, err = run(t, "--store=all", "vat", "flat", "--flat-rate=7.5", "2020-04")
+	if err == nil || !strings.Contains(err.Error(), "stores have different VAT numbers") {
+		t.Errorf("vat across stores with different VAT numbers, err=%v", err)
+	}
+	_, err = run(t, "--store=all", "--db=orders.db", "--offline", "vendor", "2020-04")
+	if err == nil || !strings.Contains(err.Error(), "--db can't be used with --store all") {
+		t.Errorf("--db with all stores, err=%v", err)
+	}
+}
+
+func TestReportDetails(t *testing.T) {
+	newStore(t, shoptest.Orders)
+
+	_, err := run(t, "vat", "flat", "--flat-rate=7.5", "--timezone=UTC", "--audit=audit.csv", "--out=q2.csv", "2020-04")
+	if err != nil {
+		t.Fatalf("vat flat --audit, err=%v", err)
+	}
+	b, err := os.ReadFile("audit.csv")
+	if err != nil {
+		t.Fatalf("ReadFile(), err=%v", err)
+	}
+	if want := shop.RuleInPeriod; !strings.Contains(string(b), want) {
+		t.Errorf("audit trail doesn't contain %q:\n%s", want, b)
+	}
+
+	for _, tt := range []struct {
+		exportPath, auditPath string
+		details, audit        bool
+	}{
+		{"", "", false, false},
+		{"q2.csv", "", false, false},
+		{"q2.xlsx", "", true, false},
+		{"q2.pdf", "audit.csv", true, true},
+	} {
+		res := &report.Result{}
+		err := addDetails(res, nil, time.Time{}, time.Time{}, shop.CalcOptions{}, tt.exportPath, tt.auditPath)
+		if err != nil {
+			t.Fatalf("addDetails(%q, %q), err=%v", tt.exportPath, tt.auditPath, err)
+		}
+		if got := len(res.Details) > 0; got != tt.details {
+			t.Errorf("addDetails(%q, %q) details = %v, want %v", tt.exportPath, tt.auditPath, got, tt.details)
+		}
+		if got := res.Audit != nil; got != tt.audit {
+			t.Errorf("addDetails(%q, %q) audit = %v, want %v", tt.exportPath, tt.auditPath, got, tt.audit)
+		}
+	}
+}
@@ -1,58 +1,261 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/r0busta/go-shopify-reports/corporatetax"
+	"github.com/r0busta/go-shopify-reports/report/render"
 	"github.com/r0busta/go-shopify-reports/sales"
-	"github.com/r0busta/go-shopify-reports/vat"
-	log "github.com/sirupsen/logrus"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/utils"
 )
 
-type VATReportCmd struct {
-	Scheme string   `arg help:"Scheme (e.g. flat)" enum:"flat"`
-	Period []string `arg required name:"date" help:"Period start and end dates (e.g. 2020-08-01 2020-10-31)"`
-	Cached bool     `name:"cached" help:"Use cached results"`
+type SyncCmd struct {
 }
 
 type CorporateTaxReportCmd struct {
-	Period []string `arg required name:"date" help:"Period start and end dates (e.g. 2020-08-01 2020-10-31)"`
-	Cached bool     `name:"cached" help:"Use cached results"`
+	Period     []string `arg required name:"date" help:"Period start and end dates (e.g. 2020-08-01 2020-10-31) or expression (e.g. last-quarter, 2020-Q3, 2021-05, ytd, 2021/22, last-quarter/stagger2)"`
+	Compare    []string `name:"compare" help:"Compare with the previous period, the same period a year ago or other dates (previous, year-ago, a period expression or e.g. 2019-08-01,2019-10-31)"`
+	Cached     bool     `name:"cached" help:"Use cached results if they cover the period"`
+	ExportPath string   `name:"out" help:"Define the path to export results as a CSV file, an Excel workbook if it ends in .xlsx or a PDF document if it ends in .pdf"`
+	AuditPath  string   `name:"audit" help:"Define the path to write the order transactions behind the figures as a CSV file, with the rule each is included or excluded by"`
 }
 
 type TagCmd struct {
 	Tags       []string `required name:"tags" help:"Tags to report sales for"`
-	Period     []string `arg required name:"date" help:"Period start and end dates (e.g. 2020-08-01 2020-10-31)"`
-	Cached     bool     `name:"cached" help:"Use cached results"`
-	ExportPath string   `name:"out" help:"Define the path to export results as a CSV file"`
+	Period     []string `arg required name:"date" help:"Period start and end dates (e.g. 2020-08-01 2020-10-31) or expression (e.g. last-quarter, 2020-Q3, 2021-05, ytd, 2021/22, last-quarter/stagger2)"`
+	Compare    []string `name:"compare" help:"Compare with the previous period, the same period a year ago or other dates (previous, year-ago, a period expression or e.g. 2019-08-01,2019-10-31)"`
+	Cached     bool     `name:"cached" help:"Use cached results if they cover the period"`
+	ExportPath string   `name:"out" help:"Define the path to export results as a CSV file, an Excel workbook if it ends in .xlsx or a PDF document if it ends in .pdf"`
 }
 
 type VendorCmd struct {
-	Period     []string `arg required name:"date" help:"Period start and end dates (e.g. 2020-08-01 2020-10-31)"`
-	Cached     bool     `name:"cached" help:"Use cached results"`
-	ExportPath string   `name:"out" help:"Define the path to export results as a CSV file"`
+	Period     []string `arg required name:"date" help:"Period start and end dates (e.g. 2020-08-01 2020-10-31) or expression (e.g. last-quarter, 2020-Q3, 2021-05, ytd, 2021/22, last-quarter/stagger2)"`
+	Compare    []string `name:"compare" help:"Compare with the previous period, the same period a year ago or other dates (previous, year-ago, a period expression or e.g. 2019-08-01,2019-10-31)"`
+	Cached     bool     `name:"cached" help:"Use cached results if they cover the period"`
+	ExportPath string   `name:"out" help:"Define the path to export results as a CSV file, an Excel workbook if it ends in .xlsx or a PDF document if it ends in .pdf"`
+}
+
+type SalesCmd struct {
+	By         []string `name:"by" help:"Dimensions to group sales by, one level each: tag, vendor, product-type, product, country, channel, discount-code, customer"`
+	Metrics    []string `name:"metrics" help:"Columns to report: orders, units, fulfilled, revenue, refunded, net, refund-ratio. Defaults to orders,fulfilled,revenue,refunded,refund-ratio, or orders,units,revenue,refunded,net by interval"`
+	Interval   string   `name:"interval" enum:",day,week,month,quarter" default:"" help:"Report each day, week, month or quarter of the period by transaction date"`
+	Period     []string `arg required name:"date" help:"Period start and end dates (e.g. 2020-08-01 2020-10-31) or expression (e.g. last-quarter, 2020-Q3, 2021-05, ytd, 2021/22, last-quarter/stagger2)"`
+	Compare    []string `name:"compare" help:"Compare with the previous period, the same period a year ago or other dates (previous, year-ago, a period expression or e.g. 2019-08-01,2019-10-31)"`
+	Cached     bool     `name:"cached" help:"Use cached results if they cover the period"`
+	ExportPath string   `name:"out" help:"Define the path to export results as a CSV file, an Excel workbook if it ends in .xlsx or a PDF document if it ends in .pdf"`
+}
+
+type ProductCmd struct {
+	Variants   bool     `name:"variants" help:"Break the products down by variant SKU"`
+	Period     []string `arg required name:"date" help:"Period start and end dates (e.g. 2020-08-01 2020-10-31) or expression (e.g. last-quarter, 2020-Q3, 2021-05, ytd, 2021/22, last-quarter/stagger2)"`
+	Cached     bool     `name:"cached" help:"Use cached results if they cover the period"`
+	ExportPath string   `name:"out" help:"Define the path to export results as a CSV file, an Excel workbook if it ends in .xlsx or a PDF document if it ends in .pdf"`
 }
 
-func (cmd *VATReportCmd) Run(ctx *Globals) error {
-	switch cmd.Scheme {
-	case "flat":
-		r := vat.FlatRateReturn{}
-		r.Report(cmd.Period, cmd.Cached)
-	default:
-		log.Fatalln("Unimplemented scheme")
+func (cmd *SyncCmd) Run(ctx *Globals, runCtx context.Context) error {
+	stores, err := selectedStores(ctx)
+	if err != nil {
+		return err
+	}
+	if stores == nil {
+		return syncStore(runCtx, shop.NewClient(), dbPath(ctx, ""), "")
+	}
+
+	for _, s := range stores {
+		// Name each store when syncing them all
+		prefix := ""
+		if len(stores) > 1 {
+			prefix = s.Name + ": "
+		}
+		err = syncStore(runCtx, shop.NewStoreClient(s), dbPath(ctx, s.Name), prefix)
+		if err != nil {
+			return fmt.Errorf("store %s: %w", s.Name, err)
+		}
 	}
 	return nil
 }
 
-func (cmd *CorporateTaxReportCmd) Run(ctx *Globals) error {
-	corporatetax.Report(cmd.Period, cmd.Cached)
+func syncStore(ctx context.Context, shopClient *shop.Client, dbPath string, prefix string) error {
+	db, err := shop.OpenOrderDB(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	n, err := shop.SyncOrders(ctx, shopClient.Order, db)
+	if err != nil {
+		return err
+	}
+
+	// Offline reports need the shop timezone for the period boundaries
+	tz, err := shopClient.Timezone(ctx)
+	if err != nil {
+		return err
+	}
+	err = db.SetTimezone(tz)
+	if err != nil {
+		return err
+	}
+
+	watermark, err := db.Watermark()
+	if err != nil {
+		return err
+	}
+	if watermark != nil {
+		fmt.Fprintf(stdout, "%sSynced %d orders, up to date as of %s\n", prefix, n, watermark.Format("Jan 2, 2006 15:04:05"))
+	} else {
+		fmt.Fprintf(stdout, "%sSynced %d orders\n", prefix, n)
+	}
 	return nil
 }
 
-func (cmd *TagCmd) Run(ctx *Globals) error {
-	sales.ByTag(cmd.Tags, cmd.Period, cmd.Cached, cmd.ExportPath)
-	return nil
+func (cmd *CorporateTaxReportCmd) Run(ctx *Globals, runCtx context.Context) error {
+	shopClient, err := newShopClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer shopClient.Close()
+
+	opts, err := calcOptions(runCtx, ctx, shopClient)
+	if err != nil {
+		return err
+	}
+
+	if cmd.AuditPath != "" && len(cmd.Compare) > 0 {
+		return fmt.Errorf("--audit isn't supported with --compare")
+	}
+
+	r, err := corporatetax.Calc(runCtx, shopClient.Order, cmd.Period, cmd.Compare, cmd.Cached, opts)
+	if err != nil {
+		return err
+	}
+	res := render.CorporateTax(r)
+	if !r.Compare {
+		err = addDetails(res, r.Orders, r.From, r.To, r.Options, cmd.ExportPath, cmd.AuditPath)
+		if err != nil {
+			return err
+		}
+	}
+	err = writeResult(ctx, shopClient, res, cmd.ExportPath)
+	if err != nil {
+		return err
+	}
+	return writeAudit(res, cmd.AuditPath)
 }
 
-func (cmd *VendorCmd) Run(ctx *Globals) error {
-	sales.ByVendor(cmd.Period, cmd.Cached, cmd.ExportPath)
-	return nil
+func (cmd *TagCmd) Run(ctx *Globals, runCtx context.Context) error {
+	shopClient, err := newShopClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer shopClient.Close()
+
+	opts, err := calcOptions(runCtx, ctx, shopClient)
+	if err != nil {
+		return err
+	}
+
+	r, err := sales.ByTag(runCtx, shopClient.Order, cmd.Tags, cmd.Period, cmd.Compare, cmd.Cached, opts)
+	if err != nil {
+		return err
+	}
+	res := render.Sales(r)
+	if !r.Compare {
+		err = addDetails(res, r.Orders, r.From, r.To, r.Options, cmd.ExportPath, "")
+		if err != nil {
+			return err
+		}
+	}
+	return writeResult(ctx, shopClient, res, cmd.ExportPath)
+}
+
+func (cmd *VendorCmd) Run(ctx *Globals, runCtx context.Context) error {
+	shopClient, err := newShopClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer shopClient.Close()
+
+	opts, err := calcOptions(runCtx, ctx, shopClient)
+	if err != nil {
+		return err
+	}
+
+	r, err := sales.ByVendor(runCtx, shopClient.Order, cmd.Period, cmd.Compare, cmd.Cached, opts)
+	if err != nil {
+		return err
+	}
+	res := render.Sales(r)
+	if !r.Compare {
+		err = addDetails(res, r.Orders, r.From, r.To, r.Options, cmd.ExportPath, "")
+		if err != nil {
+			return err
+		}
+	}
+	return writeResult(ctx, shopClient, res, cmd.ExportPath)
+}
+
+func (cmd *ProductCmd) Run(ctx *Globals, runCtx context.Context) error {
+	shopClient, err := newShopClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer shopClient.Close()
+
+	opts, err := calcOptions(runCtx, ctx, shopClient)
+	if err != nil {
+		return err
+	}
+
+	r, err := sales.ByProduct(runCtx, shopClient.Order, cmd.Period, cmd.Cached, opts, cmd.Variants)
+	if err != nil {
+		return err
+	}
+	res := render.Products(r)
+	err = addDetails(res, r.Orders, r.From, r.To, r.Options, cmd.ExportPath, "")
+	if err != nil {
+		return err
+	}
+	return writeResult(ctx, shopClient, res, cmd.ExportPath)
+}
+
+func (cmd *SalesCmd) Run(ctx *Globals, runCtx context.Context) error {
+	if len(cmd.By) == 0 && cmd.Interval == "" {
+		return fmt.Errorf("expected --by or --interval")
+	}
+	if len(cmd.Compare) > 0 && cmd.Interval != "" {
+		return fmt.Errorf("--compare can't be used with --interval")
+	}
+	if _, err := sales.ParseDimensions(cmd.By); err != nil {
+		return err
+	}
+	if _, err := sales.ParseMetrics(cmd.Metrics); err != nil {
+		return err
+	}
+
+	shopClient, err := newShopClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer shopClient.Close()
+
+	opts, err := calcOptions(runCtx, ctx, shopClient)
+	if err != nil {
+		return err
+	}
+
+	r, err := sales.By(runCtx, shopClient.Order, cmd.By, cmd.Metrics, utils.Interval(cmd.Interval), cmd.Period, cmd.Compare, cmd.Cached, opts)
+	if err != nil {
+		return err
+	}
+	res := render.Sales(r)
+	if !r.Compare {
+		err = addDetails(res, r.Orders, r.From, r.To, r.Options, cmd.ExportPath, "")
+		if err != nil {
+			return err
+		}
+	}
+	return writeResult(ctx, shopClient, res, cmd.ExportPath)
 }
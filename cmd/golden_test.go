@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the report commands")
+
+// TestGolden runs the reports against the orders of a fixture and compares their output with the golden files.
+// Fixtures are bulk query results in testdata/fixtures, one JSONL line per order or line item.
+// Run with -update to write the golden files after changing a report.
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		args    []string
+	}{
+		{"vat-flat", "q2-2020", []string{"vat", "flat", "--flat-rate=7.5", "--format=markdown", "2020-Q2"}},
+		{"vat-flat-first-year", "q2-2020", []string{"vat", "flat", "--flat-rate=7.5", "--registered=2019-06-01", "--reclaimed=12.50", "--format=markdown", "2020-Q2"}},
+		{"vat-flat-limited-cost", "q2-2020", []string{"vat", "flat", "--flat-rate=7.5", "--limited-cost=2020-05-01,2020-12-31", "--format=json", "2020-Q2"}},
+		{"vat-flat-compare", "q2-2020", []string{"vat", "flat", "--flat-rate=7.5", "--reclaimed=5", "--compare=previous", "--format=csv", "2020-05"}},
+		{"corporate-tax", "q2-2020", []string{"corporate-tax", "--format=markdown", "2020-Q2"}},
+		{"corporate-tax-compare", "q2-2020", []string{"corporate-tax", "--compare=previous", "--format=csv", "2020-05"}},
+		{"tag", "q2-2020", []string{"tag", "--tags=denim,linen,cotton", "--format=markdown", "2020-Q2"}},
+		{"tag-compare", "q2-2020", []string{"tag", "--tags=denim", "--compare=previous", "--format=csv", "2020-06"}},
+		{"vendor", "q2-2020", []string{"vendor", "--format=markdown", "2020-Q2"}},
+		{"vendor-utc", "q2-2020", []string{"vendor", "--timezone=UTC", "--format=csv", "2020-04"}},
+		{"vendor-json", "q2-2020", []string{"vendor", "--format=json", "2020-04"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture, err := os.ReadFile(filepath.Join("testdata", "fixtures", tt.fixture+".jsonl"))
+			if err != nil {
+				t.Fatalf("ReadFile(), err=%v", err)
+			}
+			// Golden paths are relative to the package, the store runs the test elsewhere
+			golden, err := filepath.Abs(filepath.Join("testdata", "golden", tt.name+".golden"))
+			if err != nil {
+				t.Fatalf("Abs(), err=%v", err)
+			}
+			newStore(t, fixture)
+
+			got, err := run(t, tt.args...)
+			if err != nil {
+				t.Fatalf("%s, err=%v", strings.Join(tt.args, " "), err)
+			}
+
+			if *update {
+				err = os.WriteFile(golden, []byte(got), 0644)
+				if err != nil {
+					t.Fatalf("WriteFile(), err=%v", err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("ReadFile(), err=%v (run with -update to create it)", err)
+			}
+			if diff := diffLines(string(want), got); diff != "" {
+				t.Errorf("%s output differs from %s (-want +got):\n%s", strings.Join(tt.args, " "), golden, diff)
+			}
+		})
+	}
+}
+
+// diffLines returns the lines of want and got that differ, or an empty string if they're the same.
+func diffLines(want, got string) string {
+	if want == got {
+		return ""
+	}
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var b strings.Builder
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w == g {
+			continue
+		}
+		if i < len(wantLines) {
+			b.WriteString("-" + w + "\n")
+		}
+		if i < len(gotLines) {
+			b.WriteString("+" + g + "\n")
+		}
+	}
+	return b.String()
+}
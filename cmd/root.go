@@ -1,14 +1,190 @@
 package cmd
 
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/fx"
+	"github.com/r0busta/go-shopify-reports/report"
+	"github.com/r0busta/go-shopify-reports/report/render"
+	"github.com/r0busta/go-shopify-reports/shop"
+)
+
+// stdout is where the commands write reports and messages.
+var stdout io.Writer = os.Stdout
+
 type Globals struct {
-	Debug bool
+	Debug   bool
+	Offline bool   `name:"offline" help:"Run reports against the local order database instead of Shopify (see sync)"`
+	DB      string `name:"db" help:"Path to the local order database. Defaults to _orders.db, or _orders-<store>.db for a store profile"`
+	Basis   string `name:"basis" default:"cash" enum:"cash,accrual" help:"Accounting basis: cash (payments and refunds when processed) or accrual (sales when ordered)"`
+
+	Currency string   `name:"currency" help:"Report currency (e.g. GBP), amounts in other currencies are converted with --fx-rates. Defaults to the orders currency"`
+	FXRates  []string `name:"fx-rates" type:"existingfile" help:"HMRC monthly exchange rates CSV files used to convert currencies"`
+
+	Presentment bool `name:"presentment" help:"Add up amounts in the currency customers paid in (presentment money) instead of the shop currency"`
+
+	Format string `name:"format" default:"table" enum:"table,json,csv,markdown" help:"Output format: table, json, csv or markdown"`
+
+	Timezone string `name:"timezone" env:"STORE_TIMEZONE" help:"IANA timezone period dates are in (e.g. Europe/London or UTC). Defaults to the shop timezone"`
+
+	Store  string `name:"store" env:"STORE_PROFILE" help:"Store profile of the stores file to report on, or all for reports consolidated across the stores. Defaults to the store set in the environment"`
+	Stores string `name:"stores" default:"stores.json" env:"STORES_FILE" help:"Stores file listing the store profiles: name, domain, token, currency, timezone and VAT number"`
 }
 
 type CLI struct {
 	Globals
 
-	VAT          VATReportCmd          `cmd help:"Print report for VAT return purposes. Example: <cmd> vat flat 2020-05-01 2020-07-31"`
+	VAT          VATCmd                `cmd help:"Print or submit report for VAT return purposes. Example: <cmd> vat flat --flat-rate=7.5 2020-05-01 2020-07-31"`
 	CorporateTax CorporateTaxReportCmd `cmd help:"Print report for Corporate tax return purposes. Example: <cmd> corporate-tax 2020-05-01 2020-07-31"`
+	Sales        SalesCmd              `cmd help:"Print sales grouped by one or more dimensions. Example: <cmd> sales --by=vendor,product-type 2020-05-01 2020-07-31"`
 	Tag          TagCmd                `cmd help:"Print report by tag. Example: <cmd> jeans sales-by-tag 2020-05-01 2020-07-31"`
 	Vendor       VendorCmd             `cmd help:"Print report by vendor. Example: <cmd> sales-by-vendor 2020-05-01 2020-07-31"`
+	Product      ProductCmd            `cmd help:"Print units and revenue by product or variant SKU. Example: <cmd> product --variants 2020-05-01 2020-07-31"`
+	Sync         SyncCmd               `cmd help:"Pull orders updated since the last sync into the local order database. Example: <cmd> sync"`
+	Serve        ServeCmd              `cmd help:"Serve the reports as web pages. Example: <cmd> serve --addr=localhost:8080"`
+}
+
+// selectedStores returns the store profiles selected with --store, or nil for the store set in the environment.
+func selectedStores(ctx *Globals) ([]shop.Store, error) {
+	if ctx.Store == "" {
+		return nil, nil
+	}
+	if ctx.Store == shop.AllStores && ctx.DB != "" {
+		return nil, fmt.Errorf("--db can't be used with --store all, each store has its own database")
+	}
+	stores, err := shop.LoadStores(ctx.Stores)
+	if err != nil {
+		return nil, err
+	}
+	return shop.FindStores(stores, ctx.Store)
+}
+
+// dbPath returns the local order database of the named store profile, or of the store set in the environment.
+func dbPath(ctx *Globals, store string) string {
+	switch {
+	case ctx.DB != "":
+		return ctx.DB
+	case store != "":
+		return fmt.Sprintf("_orders-%s.db", store)
+	default:
+		return "_orders.db"
+	}
+}
+
+// newShopClient returns a client for the selected store, or one listing the orders of all of them with --store all.
+func newShopClient(ctx *Globals) (*shop.Client, error) {
+	stores, err := selectedStores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if stores == nil {
+		if ctx.Offline {
+			return shop.NewOfflineClient(dbPath(ctx, ""))
+		}
+		return shop.NewClient(), nil
+	}
+
+	clients := []*shop.Client{}
+	for _, s := range stores {
+		var c *shop.Client
+		if ctx.Offline {
+			c, err = shop.NewOfflineStoreClient(s, dbPath(ctx, s.Name))
+			if err != nil {
+				for _, c := range clients {
+					c.Close()
+				}
+				return nil, fmt.Errorf("store %s: %w", s.Name, err)
+			}
+		} else {
+			c = shop.NewStoreClient(s)
+		}
+		clients = append(clients, c)
+	}
+
+	if ctx.Store != shop.AllStores {
+		return clients[0], nil
+	}
+	return shop.NewMultiStoreClient(clients...), nil
+}
+
+func calcOptions(runCtx context.Context, ctx *Globals, shopClient *shop.Client) (shop.CalcOptions, error) {
+	basis, err := shop.ParseBasis(ctx.Basis)
+	if err != nil {
+		return shop.CalcOptions{}, err
+	}
+	loc, err := shopClient.Location(runCtx, ctx.Timezone)
+	if err != nil {
+		return shop.CalcOptions{}, err
+	}
+	opts := shop.CalcOptions{
+		Basis:    basis,
+		Location: loc,
+		Currency: model.CurrencyCode(strings.ToUpper(ctx.Currency)),
+
+		Presentment: ctx.Presentment,
+	}
+	if opts.Currency == "" && !opts.Presentment {
+		opts.Currency = shopClient.Currency()
+	}
+
+	if len(ctx.FXRates) > 0 {
+		rates, err := fx.LoadHMRCRates(ctx.FXRates...)
+		if err != nil {
+			return shop.CalcOptions{}, err
+		}
+		opts.Rates = rates
+	}
+
+	return opts, nil
+}
+
+// writeResult writes the report result to stdout in the output format, and exports it to a CSV file or workbook if the path is set.
+func writeResult(ctx *Globals, shopClient *shop.Client, res *report.Result, exportPath string) error {
+	format, err := report.ParseFormat(ctx.Format)
+	if err != nil {
+		return err
+	}
+	res.Store = shopClient.Name()
+
+	err = report.Write(stdout, res, format)
+	if err != nil {
+		return err
+	}
+
+	if exportPath != "" {
+		return report.Export(exportPath, res)
+	}
+	return nil
+}
+
+// addDetails adds the per-order breakdown of the turnover and VAT in the period if the export writes detail tables,
+// and the audit trail if it's written, so they're only worked out when they're needed.
+func addDetails(res *report.Result, orders []*model.Order, from, to time.Time, opts shop.CalcOptions, exportPath, auditPath string) error {
+	if report.ExportsDetails(exportPath) {
+		err := render.Orders(res, orders, from, to, opts)
+		if err != nil {
+			return fmt.Errorf("error breaking down orders: %w", err)
+		}
+	}
+	if auditPath != "" {
+		err := render.Audit(res, orders, from, to, opts)
+		if err != nil {
+			return fmt.Errorf("error auditing orders: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeAudit writes the audit trail of the report result if the path is set.
+func writeAudit(res *report.Result, path string) error {
+	if path == "" {
+		return nil
+	}
+	return report.ExportAudit(path, res)
 }
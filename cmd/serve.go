@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/r0busta/go-shopify-reports/server"
+	"github.com/r0busta/go-shopify-reports/shop"
+	log "github.com/sirupsen/logrus"
+)
+
+type ServeCmd struct {
+	Addr   string `name:"addr" default:"localhost:8080" env:"REPORTS_ADDR" help:"Address to listen on"`
+	Cached bool   `name:"cached" help:"Use cached results if they cover the period"`
+}
+
+func (cmd *ServeCmd) Run(ctx *Globals, runCtx context.Context) error {
+	if ctx.Store == shop.AllStores {
+		return fmt.Errorf("reports are served for one store at a time, --store all isn't supported")
+	}
+
+	shopClient, err := newShopClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer shopClient.Close()
+
+	opts, err := calcOptions(runCtx, ctx, shopClient)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Serving reports on http://%s", cmd.Addr)
+	return http.ListenAndServe(cmd.Addr, server.New(shopClient.Name(), shopClient.Order, opts, cmd.Cached))
+}
@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/mtd"
+	"github.com/r0busta/go-shopify-reports/report/render"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/utils"
+	"github.com/r0busta/go-shopify-reports/vat"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	mtdDir = "_mtd"
+)
+
+type VATCmd struct {
+	Report VATReportCmd `cmd default:"withargs" help:"Print VAT return for the period. Example: <cmd> vat standard 2020-05-01 2020-07-31"`
+	Submit VATSubmitCmd `cmd help:"Submit VAT return for the period to HMRC via Making Tax Digital. Example: <cmd> vat submit standard --vrn=123456789 2020-05-01 2020-07-31"`
+}
+
+type VATReturnArgs struct {
+	Scheme    string   `arg help:"Scheme (e.g. flat, standard, oss)" enum:"flat,standard,oss"`
+	Period    []string `arg required name:"date" help:"Period start and end dates (e.g. 2020-08-01 2020-10-31) or expression (e.g. last-quarter, 2020-Q3, 2021-05, ytd, 2021/22, last-quarter/stagger2)"`
+	Cached    bool     `name:"cached" help:"Use cached results if they cover the period"`
+	Reclaimed string   `name:"reclaimed" default:"0" help:"VAT reclaimed on purchases, or on capital expenditure goods for the flat scheme (box 4)"`
+	Purchases string   `name:"purchases" default:"0" help:"Total value of purchases excluding VAT (box 7), standard scheme only"`
+
+	FlatRate     string   `name:"flat-rate" help:"Flat rate percentage for the business sector (e.g. 7.5), flat scheme only"`
+	RegisteredOn string   `name:"registered" help:"VAT registration date, to apply the 1% first year discount (e.g. 2020-03-01), flat scheme only"`
+	LimitedCost  []string `name:"limited-cost" help:"Start and end dates of being a limited cost trader, to apply the 16.5% rate (e.g. 2020-04-01,2021-03-31), flat scheme only"`
+
+	EURRate string `name:"eur-rate" help:"Euros per unit of the report currency, the ECB rate on the last day of the period (e.g. 1.1618), oss scheme only. Defaults to the --fx-rates rate, or 1 for reports in EUR"`
+}
+
+type VATReportCmd struct {
+	VATReturnArgs `embed`
+
+	Compare    []string `name:"compare" help:"Compare with the previous period, the same period a year ago or other dates (previous, year-ago, a period expression or e.g. 2019-08-01,2019-10-31), flat and standard schemes only"`
+	ExportPath string   `name:"out" help:"Define the path to export results as a CSV file, an Excel workbook if it ends in .xlsx or a PDF document if it ends in .pdf"`
+	AuditPath  string   `name:"audit" help:"Define the path to write the order transactions behind the figures as a CSV file, with the rule each is included or excluded by, flat and standard schemes only"`
+}
+
+type VATSubmitCmd struct {
+	VATReturnArgs `embed`
+
+	VRN          string `name:"vrn" env:"MTD_VRN" help:"VAT registration number. Defaults to the store profile's VAT number"`
+	ClientID     string `name:"client-id" env:"MTD_CLIENT_ID" help:"HMRC application client ID"`
+	ClientSecret string `name:"client-secret" env:"MTD_CLIENT_SECRET" help:"HMRC application client secret"`
+	AuthCode     string `name:"auth-code" help:"Authorization code granted by the taxpayer, needed once to obtain access tokens"`
+	APIURL       string `name:"mtd-url" default:"https://api.service.hmrc.gov.uk" env:"MTD_URL" help:"MTD API base URL (e.g. https://test-api.service.hmrc.gov.uk for the sandbox)"`
+	Finalise     bool   `name:"finalise" help:"Declare the return is true and complete, and submit it. Without it the payload is only printed"`
+}
+
+func (cmd *VATReportCmd) Run(ctx *Globals, runCtx context.Context) error {
+	_, err := storeVATNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	shopClient, err := newShopClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer shopClient.Close()
+
+	opts, err := calcOptions(runCtx, ctx, shopClient)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Scheme == "oss" {
+		if len(cmd.Compare) > 0 {
+			return fmt.Errorf("--compare isn't supported for the oss scheme")
+		}
+		if cmd.AuditPath != "" {
+			return fmt.Errorf("--audit isn't supported for the oss scheme")
+		}
+		r, err := cmd.ossReturn()
+		if err != nil {
+			return err
+		}
+		ret, err := r.Report(runCtx, shopClient.Order, cmd.Period, cmd.Cached, opts)
+		if err != nil {
+			return err
+		}
+		return writeResult(ctx, shopClient, render.OSSReturn(ret), cmd.ExportPath)
+	}
+
+	r, err := cmd.vatReturn(opts.Location)
+	if err != nil {
+		return err
+	}
+	if len(cmd.Compare) > 0 {
+		if cmd.AuditPath != "" {
+			return fmt.Errorf("--audit isn't supported with --compare")
+		}
+		c, err := vat.CompareReport(runCtx, r, shopClient.Order, cmd.Period, cmd.Compare, cmd.Cached, opts)
+		if err != nil {
+			return err
+		}
+		return writeResult(ctx, shopClient, render.VATComparison(c), cmd.ExportPath)
+	}
+
+	ret, err := r.Report(runCtx, shopClient.Order, cmd.Period, cmd.Cached, opts)
+	if err != nil {
+		return err
+	}
+	res := render.VATReturn(ret)
+	err = addDetails(res, ret.Orders, ret.From, ret.To, ret.Options, cmd.ExportPath, cmd.AuditPath)
+	if err != nil {
+		return err
+	}
+	err = writeResult(ctx, shopClient, res, cmd.ExportPath)
+	if err != nil {
+		return err
+	}
+	return writeAudit(res, cmd.AuditPath)
+}
+
+func (cmd *VATSubmitCmd) Run(ctx *Globals, runCtx context.Context) error {
+	vrn, err := storeVATNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if cmd.VRN == "" {
+		cmd.VRN = vrn
+	}
+	if cmd.VRN == "" {
+		return fmt.Errorf("VAT registration number is required, see --vrn")
+	}
+
+	shopClient, err := newShopClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer shopClient.Close()
+
+	opts, err := calcOptions(runCtx, ctx, shopClient)
+	if err != nil {
+		return err
+	}
+
+	from, to, err := utils.ParsePeriodIn(cmd.Period, opts.Location)
+	if err != nil {
+		return fmt.Errorf("error parsing period dates: %w", err)
+	}
+
+	r, err := cmd.vatReturn(opts.Location)
+	if err != nil {
+		return err
+	}
+
+	orders, err := shopClient.Order.ListCreatedBetween(runCtx, *from, *to, cmd.Cached)
+	if err != nil {
+		return fmt.Errorf("error getting orders: %w", err)
+	}
+	log.Printf("Found %d orders", len(orders))
+
+	opts, err = shop.ResolveCurrency(orders, opts)
+	if err != nil {
+		return fmt.Errorf("error resolving report currency: %w", err)
+	}
+	if opts.Currency != "" && opts.Currency != model.CurrencyCodeGbp {
+		return fmt.Errorf("VAT returns are filed in GBP, got %s, see --currency", opts.Currency)
+	}
+
+	ret, err := r.Compute(orders, *from, *to, opts)
+	if err != nil {
+		return fmt.Errorf("error calculating VAT return: %w", err)
+	}
+	err = ret.Print(stdout)
+	if err != nil {
+		return err
+	}
+
+	// The device ID and tokens are kept between runs
+	err = os.MkdirAll(mtdDir, 0700)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", mtdDir, err)
+	}
+	headers, err := mtd.FraudPreventionHeaders(filepath.Join(mtdDir, "device_id.json"))
+	if err != nil {
+		return fmt.Errorf("error collecting fraud prevention headers: %w", err)
+	}
+	auth := mtd.NewAuthenticator(cmd.APIURL, cmd.ClientID, cmd.ClientSecret, mtd.OutOfBandRedirectURI, filepath.Join(mtdDir, fmt.Sprintf("token_%s.json", cmd.VRN)))
+	if cmd.AuthCode != "" {
+		err = auth.Exchange(cmd.AuthCode)
+		if err != nil {
+			return fmt.Errorf("error exchanging authorization code: %w", err)
+		}
+	}
+
+	c := mtd.NewClient(cmd.APIURL, auth, headers)
+	obligation, err := c.FindObligation(cmd.VRN, *from, *to)
+	if errors.Is(err, mtd.ErrNotAuthorized) {
+		fmt.Fprintln(stdout, "Grant access to the VAT account, then rerun with --auth-code:", auth.AuthorizeURL(cmd.VRN))
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	payload := mtd.NewReturn(obligation.PeriodKey, ret, cmd.Finalise)
+	if !cmd.Finalise {
+		fmt.Fprintf(stdout, "Return for the period key %s due on %s, not submitted (see --finalise):\n", obligation.PeriodKey, obligation.Due)
+		return utils.WriteFormatedJSON(stdout, payload)
+	}
+
+	res, err := c.SubmitReturn(cmd.VRN, payload)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Submitted return for the period key %s, form bundle number %s, processed on %s\n", obligation.PeriodKey, res.FormBundleNumber, res.ProcessingDate)
+	return nil
+}
+
+// storeVATNumber returns the VAT number of the selected store profiles, if any.
+// A VAT return across the stores is only consolidated when they share a VAT number.
+func storeVATNumber(ctx *Globals) (string, error) {
+	stores, err := selectedStores(ctx)
+	if err != nil || stores == nil {
+		return "", err
+	}
+
+	vrn := stores[0].VATNumber
+	for _, s := range stores[1:] {
+		if s.VATNumber != vrn {
+			return "", fmt.Errorf("stores have different VAT numbers, VAT returns are per store")
+		}
+	}
+	if ctx.Store == shop.AllStores && vrn == "" {
+		return "", fmt.Errorf("VAT returns across the stores need them to share a VAT number, set vatNumber in %s", ctx.Stores)
+	}
+	return vrn, nil
+}
+
+func (args *VATReturnArgs) vatReturn(loc *time.Location) (vat.VATReturn, error) {
+	if args.Scheme == "flat" && args.FlatRate == "" {
+		return nil, fmt.Errorf("flat rate percentage is required for the flat scheme, see --flat-rate")
+	}
+	return vat.NewReturn(args.Scheme, args.params(), loc)
+}
+
+func (args *VATReturnArgs) ossReturn() (*vat.OSSReturn, error) {
+	return vat.NewOSSReturn(args.params())
+}
+
+func (args *VATReturnArgs) params() vat.Params {
+	return vat.Params{
+		Reclaimed:    args.Reclaimed,
+		Purchases:    args.Purchases,
+		FlatRate:     args.FlatRate,
+		RegisteredOn: args.RegisteredOn,
+		LimitedCost:  args.LimitedCost,
+		EURRate:      args.EURRate,
+	}
+}
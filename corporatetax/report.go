@@ -1,36 +1,137 @@
 package corporatetax
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"time"
 
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
 	"github.com/r0busta/go-shopify-reports/shop"
 	"github.com/r0busta/go-shopify-reports/utils"
+	"github.com/shopspring/decimal"
 )
 
-func Report(period []string, useCached bool) {
-	from, to, err := utils.ParsePeriod(period)
+// Result is the net turnover and VAT of orders in a period.
+type Result struct {
+	Turnover decimal.Decimal
+	Tax      decimal.Decimal
+}
+
+// StoreResult is a store's result of a report consolidated across stores.
+type StoreResult struct {
+	Store string
+	Result
+	// Prev is the store's result in the period compared with, if any.
+	Prev Result
+}
+
+// Report is the corporation tax figures of a period, and of the period compared with if Compare is set.
+type Report struct {
+	From time.Time
+	To   time.Time
+	// Options are the calculation options the figures are in, with the report currency resolved.
+	Options shop.CalcOptions
+	Orders  []*model.Order
+
+	Result
+	// Stores are the results of each store of a report consolidated across stores.
+	Stores []StoreResult
+
+	Compare  bool
+	PrevFrom time.Time
+	PrevTo   time.Time
+	Prev     Result
+
+	Methodology []string
+}
+
+// Calc calculates the net turnover and VAT of the period, compared with another period if compare is set.
+func Calc(ctx context.Context, service shop.OrderService, period []string, compare []string, useCached bool, opts shop.CalcOptions) (*Report, error) {
+	from, to, err := utils.ParsePeriodIn(period, opts.Location)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing period dates: %w", err)
+	}
+
+	orders, err := service.ListCreatedBetween(ctx, *from, *to, useCached)
+	if err != nil {
+		return nil, fmt.Errorf("error getting orders: %w", err)
+	}
+	shop.Log.Printf("Found %d orders", len(orders))
+
+	res := &Report{
+		From:   *from,
+		To:     *to,
+		Orders: orders,
+	}
+
+	prevOrders := []*model.Order{}
+	all := orders
+	if len(compare) > 0 {
+		prevFrom, prevTo, err := utils.ParseComparePeriod(compare, *from, *to)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing period to compare with: %w", err)
+		}
+		prevOrders, err = service.ListCreatedBetween(ctx, *prevFrom, *prevTo, useCached)
+		if err != nil {
+			return nil, fmt.Errorf("error getting orders: %w", err)
+		}
+		shop.Log.Printf("Found %d orders to compare with", len(prevOrders))
+
+		res.Compare, res.PrevFrom, res.PrevTo = true, *prevFrom, *prevTo
+		all = append(append([]*model.Order{}, orders...), prevOrders...)
+	}
+
+	opts, err = shop.ResolveCurrency(all, opts)
 	if err != nil {
-		log.Fatalln("error parsing period dates:", err)
+		return nil, fmt.Errorf("error resolving report currency: %w", err)
 	}
+	res.Options = opts
+	res.Methodology = methodology(opts)
 
-	shopClient := shop.NewClient()
-	orders, err := shopClient.Order.ListCreatedBetween(*from, *to, useCached)
+	res.Result, res.Prev, err = res.calc(orders, prevOrders)
 	if err != nil {
-		log.Fatalf("error getting orders: %s", err)
+		return nil, err
+	}
+	if stores, ok := service.(*shop.MultiStoreService); ok {
+		byStore, prevByStore := stores.ByStore(orders), stores.ByStore(prevOrders)
+		for i, name := range stores.Stores() {
+			cur, prev, err := res.calc(byStore[i], prevByStore[i])
+			if err != nil {
+				return nil, fmt.Errorf("store %s: %w", name, err)
+			}
+			res.Stores = append(res.Stores, StoreResult{Store: name, Result: cur, Prev: prev})
+		}
 	}
-	log.Printf("Found %d orders", len(orders))
 
-	totalTurnover, err := shop.CalcTotalNetTurnover(orders, *from, *to)
+	return res, nil
+}
+
+// calc calculates the result of the orders in the period, and of the orders to compare with if the report compares periods.
+func (r *Report) calc(orders, prevOrders []*model.Order) (Result, Result, error) {
+	cur, err := calc(orders, r.From, r.To, r.Options)
+	if err != nil || !r.Compare {
+		return cur, Result{}, err
+	}
+	prev, err := calc(prevOrders, r.PrevFrom, r.PrevTo, r.Options)
+	return cur, prev, err
+}
+
+func calc(orders []*model.Order, from, to time.Time, opts shop.CalcOptions) (Result, error) {
+	totalTurnover, err := shop.CalcTotalNetTurnover(orders, from, to, opts)
 	if err != nil {
-		log.Fatalf("Error calculating total turnover: %s", err)
+		return Result{}, fmt.Errorf("error calculating total turnover: %w", err)
 	}
 
-	totalSaleTax, err := shop.CalcTotalSaleTax(orders, *from, *to)
+	totalSaleTax, err := shop.CalcTotalSaleTax(orders, from, to, opts)
 	if err != nil {
-		log.Fatalf("Error calculating total tax: %s", err)
+		return Result{}, fmt.Errorf("error calculating total tax: %w", err)
 	}
 
-	fmt.Println("Total turnover (excl. VAT):", totalTurnover.String())
-	fmt.Println("Total tax (VAT):", totalSaleTax.String())
+	return Result{Turnover: *totalTurnover, Tax: *totalSaleTax}, nil
+}
+
+func methodology(opts shop.CalcOptions) []string {
+	return append([]string{
+		"Turnover excludes the VAT included in orders shipped to the UK at the order tax rates, orders shipped elsewhere carry no VAT.",
+	}, opts.Describe()...)
 }
@@ -0,0 +1,142 @@
+package fx
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	hmrcDateLayout = "02/01/2006"
+)
+
+type RateProvider interface {
+	// Rate returns the units of the to currency for one unit of the from currency on the date.
+	Rate(from, to model.CurrencyCode, on time.Time) (decimal.Decimal, error)
+}
+
+type rate struct {
+	currency     model.CurrencyCode
+	unitsPerBase decimal.Decimal
+	start        time.Time
+	end          time.Time
+}
+
+// TableRates is a rates table quoted against a single base currency, each rate valid for a date range.
+type TableRates struct {
+	base  model.CurrencyCode
+	rates []rate
+}
+
+var _ RateProvider = &TableRates{}
+
+func NewTableRates(base model.CurrencyCode) *TableRates {
+	return &TableRates{
+		base:  base,
+		rates: []rate{},
+	}
+}
+
+// Add sets the units of the currency for one unit of the base currency between the start and end dates inclusive.
+func (t *TableRates) Add(currency model.CurrencyCode, unitsPerBase decimal.Decimal, start, end time.Time) {
+	t.rates = append(t.rates, rate{
+		currency:     currency,
+		unitsPerBase: unitsPerBase,
+		start:        start,
+		end:          end,
+	})
+}
+
+func (t *TableRates) Rate(from, to model.CurrencyCode, on time.Time) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+
+	fromRate, err := t.unitsPerBase(from, on)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	toRate, err := t.unitsPerBase(to, on)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return toRate.Div(fromRate), nil
+}
+
+func (t *TableRates) unitsPerBase(currency model.CurrencyCode, on time.Time) (decimal.Decimal, error) {
+	if currency == t.base {
+		return decimal.NewFromInt(1), nil
+	}
+
+	day := time.Date(on.Year(), on.Month(), on.Day(), 0, 0, 0, 0, time.UTC)
+	for _, r := range t.rates {
+		if r.currency == currency && !day.Before(r.start) && !day.After(r.end) {
+			return r.unitsPerBase, nil
+		}
+	}
+	return decimal.Zero, fmt.Errorf("no %s rate on %s", currency, on.Format("2006-01-02"))
+}
+
+// LoadHMRCRates reads HMRC monthly exchange rates CSV files, quoted as currency units per £1.
+func LoadHMRCRates(paths ...string) (*TableRates, error) {
+	t := NewTableRates(model.CurrencyCodeGbp)
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, fmt.Errorf("opening rates file: %w", err)
+		}
+		err = t.ReadHMRCRates(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading rates file %s: %w", p, err)
+		}
+	}
+	return t, nil
+}
+
+func (t *TableRates) ReadHMRCRates(r io.Reader) error {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("empty rates file")
+	}
+
+	cols := map[string]int{}
+	for i, h := range records[0] {
+		cols[strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))] = i
+	}
+	codeCol, okCode := cols["Currency Code"]
+	rateCol, okRate := cols["Currency Units per £1"]
+	startCol, okStart := cols["Start Date"]
+	endCol, okEnd := cols["End Date"]
+	if !okCode || !okRate || !okStart || !okEnd {
+		return fmt.Errorf("expected `Currency Code`, `Currency Units per £1`, `Start Date` and `End Date` columns")
+	}
+
+	for i, rec := range records[1:] {
+		unitsPerBase, err := decimal.NewFromString(strings.TrimSpace(rec[rateCol]))
+		if err != nil {
+			return fmt.Errorf("line %d: parsing rate: %w", i+2, err)
+		}
+		start, err := time.Parse(hmrcDateLayout, strings.TrimSpace(rec[startCol]))
+		if err != nil {
+			return fmt.Errorf("line %d: parsing start date: %w", i+2, err)
+		}
+		end, err := time.Parse(hmrcDateLayout, strings.TrimSpace(rec[endCol]))
+		if err != nil {
+			return fmt.Errorf("line %d: parsing end date: %w", i+2, err)
+		}
+		t.Add(model.CurrencyCode(strings.TrimSpace(rec[codeCol])), unitsPerBase, start, end)
+	}
+
+	return nil
+}
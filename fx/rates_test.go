@@ -0,0 +1,49 @@
+package fx
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/shopspring/decimal"
+)
+
+const hmrcRates = `Country/Territories,Currency,Currency Code,Currency Units per £1,Start Date,End Date
+Eurozone,Euro,EUR,1.1000,01/04/2020,30/04/2020
+USA,Dollar,USD,1.2500,01/04/2020,30/04/2020
+Eurozone,Euro,EUR,1.1500,01/05/2020,31/05/2020
+`
+
+func TestTableRatesRate(t *testing.T) {
+	rates := NewTableRates(model.CurrencyCodeGbp)
+	err := rates.ReadHMRCRates(strings.NewReader(hmrcRates))
+	if err != nil {
+		t.Fatalf("ReadHMRCRates(), err=%v", err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to model.CurrencyCode
+		on       time.Time
+		want     string
+		wantErr  bool
+	}{
+		{"Same currency", model.CurrencyCodeEur, model.CurrencyCodeEur, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), "1", false},
+		{"Base to currency", model.CurrencyCodeGbp, model.CurrencyCodeEur, time.Date(2020, 4, 30, 23, 0, 0, 0, time.UTC), "1.1", false},
+		{"Currency to base in the next month", model.CurrencyCodeEur, model.CurrencyCodeGbp, time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC), "0.8695652173913043", false},
+		{"Cross rate", model.CurrencyCodeUsd, model.CurrencyCodeEur, time.Date(2020, 4, 15, 0, 0, 0, 0, time.UTC), "0.88", false},
+		{"No rate for the month", model.CurrencyCodeUsd, model.CurrencyCodeGbp, time.Date(2020, 5, 15, 0, 0, 0, 0, time.UTC), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rates.Rate(tt.from, tt.to, tt.on)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Rate(), err=%v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("Rate() = %s, want %s", got.String(), tt.want)
+			}
+		})
+	}
+}
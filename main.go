@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"io/fs"
 	"os"
+	"os/signal"
 
 	"github.com/alecthomas/kong"
 	"github.com/joho/godotenv"
 	"github.com/r0busta/go-shopify-reports/cmd"
+	"github.com/r0busta/go-shopify-reports/shop"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -15,17 +20,26 @@ func main() {
 		env = "dev"
 	}
 
+	// Stores can be set in a stores file instead (see --stores)
 	err := godotenv.Load(".env." + env + ".local")
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		log.Fatal("Error loading .env file")
 	}
 
+	// The reports log their progress to stderr
+	shop.Log = log.StandardLogger()
+
 	cli := cmd.CLI{}
 
+	// Interrupting cancels the order queries in flight
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+
 	ctx := kong.Parse(&cli,
 		kong.Name("vat"),
 		kong.Description("Get various reports from Shopify store."),
-		kong.UsageOnError())
+		kong.UsageOnError(),
+		kong.BindTo(runCtx, (*context.Context)(nil)))
 	err = ctx.Run(&cli.Globals)
+	stop()
 	ctx.FatalIfErrorf(err)
 }
@@ -0,0 +1,149 @@
+package mtd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	diskstore "github.com/r0busta/go-object-store/disk"
+)
+
+const (
+	Scope = "read:vat write:vat"
+
+	// OutOfBandRedirectURI shows the authorization code to the user instead of redirecting.
+	OutOfBandRedirectURI = "urn:ietf:wg:oauth:2.0:oob"
+)
+
+var ErrNotAuthorized = errors.New("not authorized, grant access using the authorization URL and pass the code")
+
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresIn    int       `json:"expires_in"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Authenticator obtains and refreshes the OAuth tokens of the user restricted endpoints, keeping them in a file.
+type Authenticator struct {
+	baseURL      string
+	clientID     string
+	clientSecret string
+	redirectURI  string
+	store        *diskstore.Store
+	httpClient   *http.Client
+}
+
+func NewAuthenticator(baseURL, clientID, clientSecret, redirectURI, tokenPath string) *Authenticator {
+	return &Authenticator{
+		baseURL:      baseURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURI:  redirectURI,
+		store:        diskstore.New(tokenPath),
+		httpClient:   http.DefaultClient,
+	}
+}
+
+func (a *Authenticator) AuthorizeURL(state string) string {
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", a.clientID)
+	q.Set("scope", Scope)
+	q.Set("redirect_uri", a.redirectURI)
+	q.Set("state", state)
+	return fmt.Sprintf("%s/oauth/authorize?%s", a.baseURL, q.Encode())
+}
+
+// Exchange swaps an authorization code for tokens and saves them.
+func (a *Authenticator) Exchange(code string) error {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("client_id", a.clientID)
+	form.Set("client_secret", a.clientSecret)
+	form.Set("redirect_uri", a.redirectURI)
+	form.Set("code", code)
+	return a.requestToken(form)
+}
+
+// AccessToken returns a valid access token, refreshing it if it has expired.
+func (a *Authenticator) AccessToken() (string, error) {
+	token, err := a.readToken()
+	if err != nil {
+		return "", err
+	}
+
+	if time.Now().Before(token.ExpiresAt.Add(-1 * time.Minute)) {
+		return token.AccessToken, nil
+	}
+
+	err = a.Refresh()
+	if err != nil {
+		return "", err
+	}
+
+	token, err = a.readToken()
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// Refresh swaps the saved refresh token for new tokens.
+func (a *Authenticator) Refresh() error {
+	token, err := a.readToken()
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("client_id", a.clientID)
+	form.Set("client_secret", a.clientSecret)
+	form.Set("refresh_token", token.RefreshToken)
+	err = a.requestToken(form)
+	if err != nil {
+		return fmt.Errorf("refreshing token: %w", err)
+	}
+	return nil
+}
+
+func (a *Authenticator) readToken() (*Token, error) {
+	if !a.store.FileExists() {
+		return nil, ErrNotAuthorized
+	}
+
+	token := &Token{}
+	err := a.store.Read(token)
+	if err != nil {
+		return nil, fmt.Errorf("reading token: %w", err)
+	}
+	return token, nil
+}
+
+func (a *Authenticator) requestToken(form url.Values) error {
+	resp, err := a.httpClient.Post(a.baseURL+"/oauth/token", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+
+	token := &Token{}
+	err = json.NewDecoder(resp.Body).Decode(token)
+	if err != nil {
+		return fmt.Errorf("decoding token: %w", err)
+	}
+	token.ExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+
+	return a.store.Write(token)
+}
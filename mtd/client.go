@@ -0,0 +1,198 @@
+package mtd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/r0busta/go-shopify-reports/vat"
+)
+
+const (
+	ProductionURL = "https://api.service.hmrc.gov.uk"
+	SandboxURL    = "https://test-api.service.hmrc.gov.uk"
+
+	acceptHeader = "application/vnd.hmrc.1.0+json"
+	dateLayout   = "2006-01-02"
+)
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	auth       *Authenticator
+	headers    http.Header
+}
+
+func NewClient(baseURL string, auth *Authenticator, fraudHeaders http.Header) *Client {
+	return &Client{
+		baseURL:    baseURL,
+		httpClient: http.DefaultClient,
+		auth:       auth,
+		headers:    fraudHeaders,
+	}
+}
+
+type Obligation struct {
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Due       string `json:"due"`
+	Status    string `json:"status"`
+	PeriodKey string `json:"periodKey"`
+	Received  string `json:"received,omitempty"`
+}
+
+type obligationsResponse struct {
+	Obligations []Obligation `json:"obligations"`
+}
+
+// Return is the VAT return payload of the MTD VAT API.
+type Return struct {
+	PeriodKey                    string      `json:"periodKey"`
+	VATDueSales                  json.Number `json:"vatDueSales"`
+	VATDueAcquisitions           json.Number `json:"vatDueAcquisitions"`
+	TotalVATDue                  json.Number `json:"totalVatDue"`
+	VATReclaimedCurrPeriod       json.Number `json:"vatReclaimedCurrPeriod"`
+	NetVATDue                    json.Number `json:"netVatDue"`
+	TotalValueSalesExVAT         json.Number `json:"totalValueSalesExVAT"`
+	TotalValuePurchasesExVAT     json.Number `json:"totalValuePurchasesExVAT"`
+	TotalValueGoodsSuppliedExVAT json.Number `json:"totalValueGoodsSuppliedExVAT"`
+	TotalAcquisitionsExVAT       json.Number `json:"totalAcquisitionsExVAT"`
+	Finalised                    bool        `json:"finalised"`
+}
+
+// NewReturn builds the payload for the period. The API takes pence for boxes 1 to 5 and whole pounds for boxes 6 to 9.
+func NewReturn(periodKey string, r *vat.Return, finalised bool) *Return {
+	return &Return{
+		PeriodKey:                    periodKey,
+		VATDueSales:                  json.Number(r.VATDueSales.StringFixed(2)),
+		VATDueAcquisitions:           json.Number(r.VATDueAcquisitions.StringFixed(2)),
+		TotalVATDue:                  json.Number(r.TotalVATDue.StringFixed(2)),
+		VATReclaimedCurrPeriod:       json.Number(r.VATReclaimedCurrPeriod.StringFixed(2)),
+		NetVATDue:                    json.Number(r.NetVATDue.Abs().StringFixed(2)),
+		TotalValueSalesExVAT:         json.Number(r.TotalValueSalesExVAT.Truncate(0).String()),
+		TotalValuePurchasesExVAT:     json.Number(r.TotalValuePurchasesExVAT.Truncate(0).String()),
+		TotalValueGoodsSuppliedExVAT: json.Number(r.TotalValueGoodsSuppliedExVAT.Truncate(0).String()),
+		TotalAcquisitionsExVAT:       json.Number(r.TotalAcquisitionsExVAT.Truncate(0).String()),
+		Finalised:                    finalised,
+	}
+}
+
+type SubmitResponse struct {
+	ProcessingDate   string `json:"processingDate"`
+	PaymentIndicator string `json:"paymentIndicator,omitempty"`
+	FormBundleNumber string `json:"formBundleNumber"`
+	ChargeRefNumber  string `json:"chargeRefNumber,omitempty"`
+}
+
+type APIError struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("MTD API error, status=%d, code=%s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (c *Client) Obligations(vrn string, from, to time.Time, status string) ([]Obligation, error) {
+	q := url.Values{}
+	q.Set("from", from.Format(dateLayout))
+	q.Set("to", to.Format(dateLayout))
+	if status != "" {
+		q.Set("status", status)
+	}
+
+	res := obligationsResponse{}
+	err := c.do(http.MethodGet, fmt.Sprintf("/organisations/vat/%s/obligations?%s", vrn, q.Encode()), nil, &res)
+	if err != nil {
+		return nil, fmt.Errorf("getting obligations: %w", err)
+	}
+	return res.Obligations, nil
+}
+
+// FindObligation returns the open obligation for exactly the from and to dates.
+func (c *Client) FindObligation(vrn string, from, to time.Time) (*Obligation, error) {
+	obligations, err := c.Obligations(vrn, from, to, "O")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range obligations {
+		if o.Start == from.Format(dateLayout) && o.End == to.Format(dateLayout) {
+			return &o, nil
+		}
+	}
+	return nil, fmt.Errorf("no open obligation for the period %s to %s", from.Format(dateLayout), to.Format(dateLayout))
+}
+
+func (c *Client) SubmitReturn(vrn string, r *Return) (*SubmitResponse, error) {
+	res := &SubmitResponse{}
+	err := c.do(http.MethodPost, fmt.Sprintf("/organisations/vat/%s/returns", vrn), r, res)
+	if err != nil {
+		return nil, fmt.Errorf("submitting return: %w", err)
+	}
+	return res, nil
+}
+
+func (c *Client) do(method, path string, body interface{}, out interface{}) error {
+	err := c.send(method, path, body, out)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		err = c.auth.Refresh()
+		if err != nil {
+			return err
+		}
+		return c.send(method, path, body, out)
+	}
+
+	return err
+}
+
+func (c *Client) send(method, path string, body interface{}, out interface{}) error {
+	token, err := c.auth.AccessToken()
+	if err != nil {
+		return fmt.Errorf("getting access token: %w", err)
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", acceptHeader)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,136 @@
+package mtd_test
+
+import (
+	"errors"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-reports/mtd"
+	"github.com/r0busta/go-shopify-reports/mtd/mtdtest"
+	"github.com/r0busta/go-shopify-reports/vat"
+	"github.com/shopspring/decimal"
+)
+
+func TestSubmitReturn(t *testing.T) {
+	const vrn = "123456789"
+
+	srv := mtdtest.NewServer(vrn, []mtd.Obligation{
+		{Start: "2020-01-01", End: "2020-03-31", Due: "2020-05-07", Status: "F", PeriodKey: "20A1", Received: "2020-05-01"},
+		{Start: "2020-04-01", End: "2020-06-30", Due: "2020-08-07", Status: "O", PeriodKey: "20A2"},
+	})
+	defer srv.Close()
+
+	dir := t.TempDir()
+	headers, err := mtd.FraudPreventionHeaders(filepath.Join(dir, "device_id.json"))
+	if err != nil {
+		t.Fatalf("FraudPreventionHeaders(), err=%v", err)
+	}
+	auth := mtd.NewAuthenticator(srv.URL, "client-id", "client-secret", mtd.OutOfBandRedirectURI, filepath.Join(dir, "token.json"))
+	c := mtd.NewClient(srv.URL, auth, headers)
+
+	from := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 6, 30, 23, 59, 59, 0, time.UTC)
+
+	_, err = c.FindObligation(vrn, from, to)
+	if !errors.Is(err, mtd.ErrNotAuthorized) {
+		t.Fatalf("FindObligation() before authorizing, err=%v, want %v", err, mtd.ErrNotAuthorized)
+	}
+
+	err = auth.Exchange(mtdtest.AuthCode)
+	if err != nil {
+		t.Fatalf("Exchange(), err=%v", err)
+	}
+
+	obligation, err := c.FindObligation(vrn, from, to)
+	if err != nil {
+		t.Fatalf("FindObligation(), err=%v", err)
+	}
+	if obligation.PeriodKey != "20A2" {
+		t.Errorf("FindObligation() period key = %s, want 20A2", obligation.PeriodKey)
+	}
+
+	r := &vat.Return{
+		VATDueSales:          decimal.RequireFromString("2.50"),
+		TotalVATDue:          decimal.RequireFromString("2.50"),
+		NetVATDue:            decimal.RequireFromString("2.50"),
+		TotalValueSalesExVAT: decimal.RequireFromString("12.99"),
+	}
+
+	srv.ExpireTokens()
+
+	res, err := c.SubmitReturn(vrn, mtd.NewReturn(obligation.PeriodKey, r, true))
+	if err != nil {
+		t.Fatalf("SubmitReturn(), err=%v", err)
+	}
+	if res.FormBundleNumber == "" {
+		t.Errorf("SubmitReturn() form bundle number is empty")
+	}
+
+	submissions := srv.Submissions()
+	if len(submissions) != 1 {
+		t.Fatalf("submissions = %d, want 1", len(submissions))
+	}
+	if got := submissions[0].VATDueSales.String(); got != "2.50" {
+		t.Errorf("submitted box 1 = %s, want 2.50", got)
+	}
+	if got := submissions[0].TotalValueSalesExVAT.String(); got != "12" {
+		t.Errorf("submitted box 6 = %s, want 12", got)
+	}
+
+	_, err = c.SubmitReturn(vrn, mtd.NewReturn(obligation.PeriodKey, r, true))
+	var apiErr *mtd.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "DUPLICATE_SUBMISSION" {
+		t.Errorf("SubmitReturn() twice, err=%v, want DUPLICATE_SUBMISSION", err)
+	}
+}
+
+func TestFraudPreventionHeaders(t *testing.T) {
+	headers, err := mtd.FraudPreventionHeaders(filepath.Join(t.TempDir(), "device_id.json"))
+	if err != nil {
+		t.Fatalf("FraudPreventionHeaders(), err=%v", err)
+	}
+
+	for _, name := range []string{
+		"Gov-Client-Connection-Method",
+		"Gov-Client-Device-ID",
+		"Gov-Client-Timezone",
+		"Gov-Client-User-Agent",
+		"Gov-Client-Screens",
+		"Gov-Client-Window-Size",
+		"Gov-Client-Multi-Factor",
+		"Gov-Vendor-Version",
+		"Gov-Vendor-Product-Name",
+		"Gov-Vendor-License-IDs",
+	} {
+		if len(headers.Values(name)) == 0 {
+			t.Errorf("%s header not set", name)
+		}
+	}
+	if got := headers.Get("Gov-Client-Connection-Method"); got != mtd.ConnectionMethod {
+		t.Errorf("Gov-Client-Connection-Method = %q, want %q", got, mtd.ConnectionMethod)
+	}
+}
+
+func TestFraudPreventionHeadersOSVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("compares with uname")
+	}
+	out, err := exec.Command("uname", "-r").Output()
+	if err != nil {
+		t.Skipf("uname, err=%v", err)
+	}
+
+	headers, err := mtd.FraudPreventionHeaders(filepath.Join(t.TempDir(), "device_id.json"))
+	if err != nil {
+		t.Fatalf("FraudPreventionHeaders(), err=%v", err)
+	}
+	want := "os-version=" + url.QueryEscape(strings.TrimSpace(string(out)))
+	if got := headers.Get("Gov-Client-User-Agent"); !strings.Contains(got, want) {
+		t.Errorf("Gov-Client-User-Agent = %q, want it to contain %q", got, want)
+	}
+}
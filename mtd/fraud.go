@@ -0,0 +1,148 @@
+package mtd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strings"
+	"time"
+
+	diskstore "github.com/r0busta/go-object-store/disk"
+)
+
+const (
+	ConnectionMethod = "DESKTOP_APP_DIRECT"
+
+	ProductName    = "go-shopify-reports"
+	ProductVersion = "1.0.0"
+)
+
+// FraudPreventionHeaders collects the HMRC fraud prevention headers for a desktop application
+// connecting directly to the API. The device ID is generated once and kept in deviceIDPath.
+func FraudPreventionHeaders(deviceIDPath string) (http.Header, error) {
+	deviceID, err := getDeviceID(deviceIDPath)
+	if err != nil {
+		return nil, fmt.Errorf("getting device ID: %w", err)
+	}
+
+	h := http.Header{}
+	h.Set("Gov-Client-Connection-Method", ConnectionMethod)
+	h.Set("Gov-Client-Device-ID", deviceID)
+	h.Set("Gov-Client-Timezone", formatTimezone(time.Now()))
+	h.Set("Gov-Client-User-Agent", fmt.Sprintf("os-family=%s&os-version=%s&device-manufacturer=&device-model=", url.QueryEscape(runtime.GOOS), url.QueryEscape(osVersion())))
+	h.Set("Gov-Vendor-Version", fmt.Sprintf("%s=%s", ProductName, url.QueryEscape(ProductVersion)))
+	h.Set("Gov-Vendor-Product-Name", url.QueryEscape(ProductName))
+	// The reports are open source and free of licences
+	h.Set("Gov-Vendor-License-IDs", "")
+
+	// The command line has no screen or window of its own to measure, and it signs in to HMRC without
+	// multi-factor authentication, so these are sent empty as HMRC asks when a value can't be collected
+	h.Set("Gov-Client-Screens", "")
+	h.Set("Gov-Client-Window-Size", "")
+	h.Set("Gov-Client-Multi-Factor", "")
+
+	if u, err := user.Current(); err == nil {
+		h.Set("Gov-Client-User-IDs", fmt.Sprintf("os=%s", url.QueryEscape(u.Username)))
+	}
+
+	ips, macs := localAddresses()
+	if len(ips) > 0 {
+		h.Set("Gov-Client-Local-IPs", strings.Join(ips, ","))
+	}
+	if len(macs) > 0 {
+		h.Set("Gov-Client-MAC-Addresses", strings.Join(macs, ","))
+	}
+
+	return h, nil
+}
+
+// osVersion returns the version of the operating system, e.g. the kernel release on Linux and macOS,
+// or an empty string if it can't be read.
+func osVersion() string {
+	var out []byte
+	var err error
+	if runtime.GOOS == "windows" {
+		out, err = exec.Command("cmd", "/c", "ver").Output()
+	} else {
+		out, err = exec.Command("uname", "-r").Output()
+	}
+	if err != nil {
+		return ""
+	}
+
+	v := strings.TrimSpace(string(out))
+	// Windows prints e.g. "Microsoft Windows [Version 10.0.19045.2965]"
+	if i := strings.Index(v, "[Version "); i >= 0 {
+		v = strings.TrimSuffix(v[i+len("[Version "):], "]")
+	}
+	return v
+}
+
+func getDeviceID(path string) (string, error) {
+	store := diskstore.New(path)
+
+	var id string
+	if store.FileExists() {
+		err := store.Read(&id)
+		if err != nil {
+			return "", err
+		}
+		return id, nil
+	}
+
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	id = fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+
+	return id, store.Write(id)
+}
+
+func formatTimezone(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("UTC%s%02d:%02d", sign, offset/3600, offset%3600/60)
+}
+
+func localAddresses() ([]string, []string) {
+	ips := []string{}
+	macs := []string{}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ips, macs
+	}
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		if len(iface.HardwareAddr) > 0 {
+			macs = append(macs, url.QueryEscape(iface.HardwareAddr.String()))
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok {
+				ips = append(ips, url.QueryEscape(ipNet.IP.String()))
+			}
+		}
+	}
+
+	return ips, macs
+}
@@ -0,0 +1,236 @@
+// Package mtdtest provides a fake HMRC Making Tax Digital VAT API for testing the submission flow offline.
+package mtdtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/r0busta/go-shopify-reports/mtd"
+	"github.com/r0busta/go-shopify-reports/rand"
+)
+
+const (
+	AuthCode = "test-auth-code"
+
+	tokenLifetime = 4 * time.Hour
+)
+
+type Server struct {
+	*httptest.Server
+
+	vrn string
+
+	mu            sync.Mutex
+	obligations   []mtd.Obligation
+	submissions   []mtd.Return
+	accessTokens  map[string]time.Time
+	refreshTokens map[string]bool
+}
+
+// NewServer starts a fake API for the VAT registration number with the given obligations.
+func NewServer(vrn string, obligations []mtd.Obligation) *Server {
+	s := &Server{
+		vrn:           vrn,
+		obligations:   obligations,
+		accessTokens:  map[string]time.Time{},
+		refreshTokens: map[string]bool{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/token", s.handleToken)
+	mux.HandleFunc(fmt.Sprintf("/organisations/vat/%s/obligations", vrn), s.handleObligations)
+	mux.HandleFunc(fmt.Sprintf("/organisations/vat/%s/returns", vrn), s.handleReturns)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "MATCHING_RESOURCE_NOT_FOUND", "Matching resource not found")
+	})
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *Server) Submissions() []mtd.Return {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]mtd.Return{}, s.submissions...)
+}
+
+// ExpireTokens makes the issued access tokens expire, so the client has to refresh them.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for t := range s.accessTokens {
+		s.accessTokens[t] = time.Now().Add(-time.Second)
+	}
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		if r.PostForm.Get("code") != AuthCode {
+			writeError(w, http.StatusBadRequest, "invalid_grant", "Invalid authorization code")
+			return
+		}
+	case "refresh_token":
+		token := r.PostForm.Get("refresh_token")
+		if !s.refreshTokens[token] {
+			writeError(w, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+			return
+		}
+		delete(s.refreshTokens, token)
+	default:
+		writeError(w, http.StatusBadRequest, "unsupported_grant_type", "Unsupported grant type")
+		return
+	}
+
+	token := mtd.Token{
+		AccessToken:  rand.String(32),
+		RefreshToken: rand.String(32),
+		ExpiresIn:    int(tokenLifetime.Seconds()),
+	}
+	s.accessTokens[token.AccessToken] = time.Now().Add(tokenLifetime)
+	s.refreshTokens[token.RefreshToken] = true
+
+	writeJSON(w, http.StatusOK, token)
+}
+
+func (s *Server) handleObligations(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r) {
+		return
+	}
+
+	from, err := time.Parse("2006-01-02", r.URL.Query().Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_DATE_FROM", "Invalid date from")
+		return
+	}
+	to, err := time.Parse("2006-01-02", r.URL.Query().Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_DATE_TO", "Invalid date to")
+		return
+	}
+	status := r.URL.Query().Get("status")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := []mtd.Obligation{}
+	for _, o := range s.obligations {
+		start, _ := time.Parse("2006-01-02", o.Start)
+		end, _ := time.Parse("2006-01-02", o.End)
+		if end.Before(from) || start.After(to) {
+			continue
+		}
+		if status != "" && o.Status != status {
+			continue
+		}
+		res = append(res, o)
+	}
+
+	if len(res) == 0 {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource could not be found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"obligations": res})
+}
+
+func (s *Server) handleReturns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed")
+		return
+	}
+	if !s.authorize(w, r) {
+		return
+	}
+
+	ret := mtd.Return{}
+	if err := json.NewDecoder(r.Body).Decode(&ret); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	if !ret.Finalised {
+		writeError(w, http.StatusForbidden, "NOT_FINALISED", "The return cannot be accepted without a declaration it is finalised")
+		return
+	}
+	for _, n := range []json.Number{ret.TotalValueSalesExVAT, ret.TotalValuePurchasesExVAT, ret.TotalValueGoodsSuppliedExVAT, ret.TotalAcquisitionsExVAT} {
+		if strings.Contains(n.String(), ".") {
+			writeError(w, http.StatusBadRequest, "INVALID_MONETARY_AMOUNT", "Boxes 6 to 9 must be whole pounds")
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, o := range s.obligations {
+		if o.PeriodKey != ret.PeriodKey {
+			continue
+		}
+		if o.Status == "F" {
+			writeError(w, http.StatusForbidden, "DUPLICATE_SUBMISSION", "The VAT return was already submitted for the given period")
+			return
+		}
+
+		s.obligations[i].Status = "F"
+		s.obligations[i].Received = time.Now().Format("2006-01-02")
+		s.submissions = append(s.submissions, ret)
+
+		writeJSON(w, http.StatusCreated, mtd.SubmitResponse{
+			ProcessingDate:   time.Now().UTC().Format(time.RFC3339),
+			PaymentIndicator: "BANK",
+			FormBundleNumber: fmt.Sprintf("%012d", len(s.submissions)),
+		})
+		return
+	}
+
+	writeError(w, http.StatusForbidden, "INVALID_PERIODKEY", "Period key does not match any obligation")
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Accept") != "application/vnd.hmrc.1.0+json" {
+		writeError(w, http.StatusNotAcceptable, "ACCEPT_HEADER_INVALID", "The accept header is missing or invalid")
+		return false
+	}
+	if r.Header.Get("Gov-Client-Connection-Method") == "" || r.Header.Get("Gov-Client-Device-ID") == "" {
+		writeError(w, http.StatusBadRequest, "INVALID_HEADER", "Fraud prevention headers are missing")
+		return false
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	s.mu.Lock()
+	expiresAt, ok := s.accessTokens[token]
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(expiresAt) {
+		writeError(w, http.StatusUnauthorized, "INVALID_CREDENTIALS", "Invalid Authentication information provided")
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]string{"code": code, "message": message})
+}
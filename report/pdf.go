@@ -0,0 +1,200 @@
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/r0busta/go-shopify-reports/utils"
+)
+
+const (
+	pdfFont       = "Helvetica"
+	pdfLineHeight = 5.0
+	pdfRowHeight  = 6.0
+	pdfCellPad    = 2.0
+)
+
+// document writes a result as a printable PDF record: the report details and figures, the tables, the methodology,
+// then the detail tables as appendices. It only uses the core PDF fonts, so it needs nothing but the result.
+type document struct {
+	pdf *gofpdf.Fpdf
+	r   *Result
+	tr  func(string) string
+}
+
+func writePDF(w io.Writer, r *Result) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	doc := &document{pdf: pdf, r: r, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+
+	now := utils.Now()
+	pdf.SetCreationDate(now)
+	pdf.SetModificationDate(now)
+	pdf.SetCatalogSort(true)
+	pdf.SetTitle(r.Title, true)
+	pdf.SetCreator("shopify-reports", true)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont(pdfFont, "I", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("%s, %s - page %d of {nb}", doc.tr(r.Title), r.Period, pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	doc.writeHeader(now.Format("2006-01-02 15:04 MST"))
+	for _, t := range r.Tables {
+		doc.writeTable(t.Name, t)
+	}
+	if len(r.Summary) > 0 {
+		doc.writeFields()
+	}
+	if len(r.Methodology) > 0 {
+		doc.writeMethodology()
+	}
+	for _, t := range r.Details {
+		pdf.AddPage()
+		doc.writeTable("Appendix: "+t.Name, t)
+	}
+
+	return pdf.Output(w)
+}
+
+func (doc *document) writeHeader(generated string) {
+	pdf := doc.pdf
+	pdf.SetFont(pdfFont, "B", 16)
+	pdf.MultiCell(0, 8, doc.tr(doc.r.Title), "", "L", false)
+	pdf.Ln(2)
+
+	lines := [][2]string{}
+	if doc.r.Store != "" {
+		lines = append(lines, [2]string{"Store", doc.r.Store})
+	}
+	lines = append(lines, [2]string{"Period", doc.r.Period.String()})
+	if doc.r.Compare != nil {
+		lines = append(lines, [2]string{"Compared with", doc.r.Compare.String()})
+	}
+	if doc.r.Currency != "" {
+		lines = append(lines, [2]string{"Currency", string(doc.r.Currency)})
+	}
+	lines = append(lines, [2]string{"Generated", generated})
+
+	for _, l := range lines {
+		pdf.SetFont(pdfFont, "B", 10)
+		pdf.CellFormat(35, pdfLineHeight, doc.tr(l[0]), "", 0, "L", false, 0, "")
+		pdf.SetFont(pdfFont, "", 10)
+		pdf.CellFormat(0, pdfLineHeight, doc.tr(l[1]), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+}
+
+func (doc *document) writeHeading(s string) {
+	doc.pdf.SetFont(pdfFont, "B", 12)
+	doc.pdf.CellFormat(0, 8, doc.tr(s), "", 1, "L", false, 0, "")
+}
+
+func (doc *document) writeFields() {
+	t := &Table{Columns: []string{"Figure", "Value"}}
+	for _, f := range doc.r.Summary {
+		t.AddRow(Text(f.Name), f.Value)
+	}
+	doc.writeTable("Figures", t)
+}
+
+func (doc *document) writeMethodology() {
+	doc.writeHeading("Methodology")
+	doc.pdf.SetFont(pdfFont, "", 10)
+	for _, p := range doc.r.Methodology {
+		doc.pdf.MultiCell(0, pdfLineHeight, doc.tr(p), "", "L", false)
+		doc.pdf.Ln(1)
+	}
+	doc.pdf.Ln(4)
+}
+
+// writeTable writes the table with columns sized to their content, repeating the header on every page.
+func (doc *document) writeTable(heading string, t *Table) {
+	pdf := doc.pdf
+	if heading != "" {
+		doc.writeHeading(heading)
+	}
+	if len(t.Columns) == 0 {
+		return
+	}
+
+	pdf.SetFont(pdfFont, "", 9)
+	widths := doc.columnWidths(t)
+	header := func() {
+		pdf.SetFont(pdfFont, "B", 9)
+		pdf.SetFillColor(217, 225, 242)
+		for i, c := range t.Columns {
+			pdf.CellFormat(widths[i], pdfRowHeight, doc.fit(c, widths[i]), "B", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont(pdfFont, "", 9)
+	}
+
+	header()
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	for _, row := range t.Rows {
+		if pdf.GetY()+pdfRowHeight > pageHeight-bottom {
+			pdf.AddPage()
+			header()
+		}
+		for i := range t.Columns {
+			c := Cell{}
+			if i < len(row) {
+				c = row[i]
+			}
+			align := "R"
+			if c.Kind == KindText {
+				align = "L"
+			}
+			pdf.CellFormat(widths[i], pdfRowHeight, doc.fit(c.Text, widths[i]), "", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.Ln(6)
+}
+
+// columnWidths shares the page width between the columns in proportion to their longest text.
+func (doc *document) columnWidths(t *Table) []float64 {
+	pageWidth, _ := doc.pdf.GetPageSize()
+	left, _, right, _ := doc.pdf.GetMargins()
+	available := pageWidth - left - right
+
+	widths := make([]float64, len(t.Columns))
+	for i, c := range t.Columns {
+		widths[i] = doc.pdf.GetStringWidth(doc.tr(c)) + 2*pdfCellPad
+	}
+	for _, row := range t.Rows {
+		for i, c := range row {
+			if i >= len(widths) {
+				break
+			}
+			if w := doc.pdf.GetStringWidth(doc.tr(c.Text)) + 2*pdfCellPad; w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	total := 0.0
+	for _, w := range widths {
+		total += w
+	}
+	for i := range widths {
+		widths[i] = widths[i] / total * available
+	}
+	return widths
+}
+
+// fit cuts the text to the width, marking it cut with an ellipsis.
+func (doc *document) fit(s string, width float64) string {
+	s = doc.tr(s)
+	if doc.pdf.GetStringWidth(s)+2*pdfCellPad <= width {
+		return s
+	}
+	for len(s) > 0 && doc.pdf.GetStringWidth(s+"...")+2*pdfCellPad > width {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
@@ -0,0 +1,38 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-reports/utils"
+	"github.com/shopspring/decimal"
+)
+
+func TestWritePDF(t *testing.T) {
+	now := utils.Now
+	defer func() { utils.Now = now }()
+	utils.Now = func() time.Time { return time.Date(2020, 5, 2, 9, 0, 0, 0, time.UTC) }
+
+	res := newResult()
+	res.Store = "acme-store"
+	res.Methodology = []string{"Cash basis: sales and refunds are the successful payments and refunds processed in the period."}
+	orders := res.AddDetail("Orders", "Order", "Turnover (incl. VAT)")
+	for i := 0; i < 100; i++ {
+		orders.AddRow(Text("#1001 – a long order name that doesn't fit in its column at all"), Amount(decimal.RequireFromString("12.34")))
+	}
+
+	var a, b bytes.Buffer
+	if err := writePDF(&a, res); err != nil {
+		t.Fatalf("writePDF(), err=%v", err)
+	}
+	if err := writePDF(&b, res); err != nil {
+		t.Fatalf("writePDF(), err=%v", err)
+	}
+	if !bytes.HasPrefix(a.Bytes(), []byte("%PDF-")) {
+		t.Errorf("writePDF() = %q..., want a PDF document", a.Bytes()[:8])
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Errorf("writePDF() differs between runs of the same result")
+	}
+}
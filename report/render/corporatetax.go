@@ -0,0 +1,59 @@
+package render
+
+import (
+	"github.com/r0busta/go-shopify-reports/corporatetax"
+	"github.com/r0busta/go-shopify-reports/report"
+	"github.com/shopspring/decimal"
+)
+
+// CorporateTax lays out the net turnover and VAT of the period, with each store's of a consolidated report.
+// Compared with another period, each figure has a row with the change.
+func CorporateTax(r *corporatetax.Report) *report.Result {
+	res := &report.Result{
+		Title:       "Corporation tax",
+		Currency:    r.Options.Currency,
+		Period:      report.Period{From: r.From, To: r.To},
+		Methodology: r.Methodology,
+	}
+
+	if !r.Compare {
+		res.AddField("Total turnover (excl. VAT)", report.Amount(r.Turnover))
+		res.AddField("Total tax (VAT)", report.Amount(r.Tax))
+		if len(r.Stores) > 0 {
+			tab := res.AddTable("Stores", "Store", "Turnover (excl. VAT)", "Tax (VAT)")
+			for _, s := range r.Stores {
+				tab.AddRow(report.Text(s.Store), report.Amount(s.Turnover), report.Amount(s.Tax))
+			}
+		}
+		return res
+	}
+
+	res.Compare = &report.Period{From: r.PrevFrom, To: r.PrevTo}
+
+	// Consolidated reports across stores compare each store, then all of them
+	sections := []corporatetax.StoreResult{{Result: r.Result, Prev: r.Prev}}
+	headers := []string{"Item", res.Period.String(), res.Compare.String(), "Change", "Change %"}
+	if len(r.Stores) > 0 {
+		sections = append(append([]corporatetax.StoreResult{}, r.Stores...), corporatetax.StoreResult{Store: "All stores", Result: r.Result, Prev: r.Prev})
+		headers = append([]string{"Store"}, headers...)
+	}
+
+	tab := res.AddTable("", headers...)
+	for _, sec := range sections {
+		for _, line := range []struct {
+			name      string
+			cur, prev decimal.Decimal
+		}{
+			{"Total turnover (excl. VAT)", sec.Turnover, sec.Prev.Turnover},
+			{"Total tax (VAT)", sec.Tax, sec.Prev.Tax},
+		} {
+			change, pct := report.Change(line.cur, line.prev, 2)
+			row := []report.Cell{report.Text(line.name), report.Amount(line.cur), report.Amount(line.prev), change, pct}
+			if sec.Store != "" {
+				row = append([]report.Cell{report.Text(sec.Store)}, row...)
+			}
+			tab.AddRow(row...)
+		}
+	}
+	return res
+}
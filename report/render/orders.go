@@ -0,0 +1,96 @@
+package render
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/report"
+	"github.com/r0busta/go-shopify-reports/shop"
+)
+
+// Orders adds the per-order breakdown of the turnover and VAT in the period to the result as a detail table.
+// Orders with nothing recognised in the period are left out.
+func Orders(res *report.Result, orders []*model.Order, from, to time.Time, opts shop.CalcOptions) error {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	tab := res.AddDetail("Orders", "Order", "Created", "Country", "Turnover (incl. VAT)", "VAT", "Net turnover")
+	for _, o := range orders {
+		turnover, err := shop.CalcOrderTurnover(o, from, to, opts)
+		if err != nil {
+			return fmt.Errorf("calculating order %s turnover: %w", o.Name, err)
+		}
+		tax, err := shop.CalcOrderSaleTax(o, from, to, opts)
+		if err != nil {
+			return fmt.Errorf("calculating order %s tax: %w", o.Name, err)
+		}
+		if turnover.IsZero() && tax.IsZero() {
+			continue
+		}
+
+		createdAt, err := time.Parse(shop.ISO8601Layout, o.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("error parsing order %s created at time: %w", o.Name, err)
+		}
+		country := ""
+		if code := shop.GetOrderCountryCode(o); code != nil {
+			country = code.String()
+		}
+
+		tab.AddRow(
+			report.Text(o.Name),
+			report.Text(createdAt.In(loc).Format("2006-01-02 15:04")),
+			report.Text(country),
+			report.Amount(turnover),
+			report.Amount(*tax),
+			report.Amount(turnover.Sub(*tax)),
+		)
+	}
+	return nil
+}
+
+// Audit adds the order transactions considered for the turnover and VAT in the period to the result as the audit trail,
+// with the rule each one is included or excluded by.
+func Audit(res *report.Result, orders []*model.Order, from, to time.Time, opts shop.CalcOptions) error {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	entries, err := shop.AuditTransactions(orders, from, to, opts)
+	if err != nil {
+		return err
+	}
+
+	res.Audit = &report.Table{
+		Name:    "Audit",
+		Columns: []string{"Order", "Transaction Kind", "Status", "Processed", "Amount", "Currency", "Included", "Rule", "Turnover (incl. VAT)", "VAT"},
+		Rows:    [][]report.Cell{},
+	}
+	for _, e := range entries {
+		processed := ""
+		if e.ProcessedAt != nil {
+			processed = e.ProcessedAt.In(loc).Format("2006-01-02 15:04:05")
+		}
+		included := "no"
+		if e.Included {
+			included = "yes"
+		}
+		res.Audit.AddRow(
+			report.Text(e.Order),
+			report.Text(string(e.Kind)),
+			report.Text(string(e.Status)),
+			report.Text(processed),
+			report.Amount(e.Amount.Amount),
+			report.Text(string(e.Amount.Currency)),
+			report.Text(included),
+			report.Text(e.Rule),
+			report.Amount(e.Turnover),
+			report.Amount(e.Tax),
+		)
+	}
+	return nil
+}
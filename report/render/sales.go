@@ -0,0 +1,125 @@
+package render
+
+import (
+	"github.com/r0busta/go-shopify-reports/report"
+	"github.com/r0busta/go-shopify-reports/sales"
+	"github.com/shopspring/decimal"
+)
+
+// Sales lays out a row per group, with a column per dimension and metric. Compared with another period,
+// each metric has the value of the period compared with and the change next to it.
+func Sales(r *sales.Report) *report.Result {
+	res := &report.Result{
+		Title:    r.Title,
+		Currency: r.Options.Currency,
+		Period:   report.Period{From: r.From, To: r.To},
+	}
+	if r.Compare {
+		res.Compare = &report.Period{From: r.PrevFrom, To: r.PrevTo}
+	}
+
+	headers := []string{}
+	if r.Interval != "" {
+		headers = append(headers, "Period")
+	}
+	if r.ByStore {
+		headers = append(headers, "Store")
+	}
+	for _, d := range r.Dimensions {
+		headers = append(headers, d.Header)
+	}
+	for _, m := range r.Metrics {
+		if r.Compare {
+			headers = append(headers, m.Header, m.Header+" (Prev)", m.Header+" Change", m.Header+" Change %")
+		} else {
+			headers = append(headers, m.Header)
+		}
+	}
+
+	tab := res.AddTable("", headers...)
+	for _, row := range r.Rows {
+		cells := []report.Cell{}
+		if r.Interval != "" {
+			cells = append(cells, report.Text(row.Period))
+		}
+		if r.ByStore {
+			cells = append(cells, report.Text(row.Store))
+		}
+		cells = append(cells, groupLabels(row, r.Dimensions)...)
+		for _, m := range r.Metrics {
+			cur := m.Value(row.Group)
+			if !r.Compare {
+				cells = append(cells, metricCell(m, cur))
+				continue
+			}
+			prev := m.Value(row.Prev)
+			change, pct := report.Change(cur, prev, m.Places)
+			cells = append(cells, metricCell(m, cur), metricCell(m, prev), change, pct)
+		}
+		tab.AddRow(cells...)
+	}
+
+	return res
+}
+
+// Products lays out the units and revenue of each product, or variant SKU.
+func Products(r *sales.ProductReport) *report.Result {
+	headers := []string{"Product", "Units Ordered", "Units Fulfilled", "Units Refunded", "Gross Revenue", "Refunded", "Net Revenue"}
+	if r.ByVariant {
+		headers = append([]string{"Product", "Variant", "SKU"}, headers[1:]...)
+	}
+	res := &report.Result{
+		Title:    "Sales by product",
+		Currency: r.Options.Currency,
+		Period:   report.Period{From: r.From, To: r.To},
+	}
+	tab := res.AddTable("", headers...)
+	for _, p := range r.Products {
+		row := []report.Cell{report.Text(p.Product)}
+		if r.ByVariant {
+			row = append(row, report.Text(p.Variant), report.Text(p.Sku))
+		}
+		row = append(row,
+			report.Int(p.Ordered),
+			report.Int(p.Fulfilled),
+			report.Int(p.Refunded),
+			report.Amount(p.Gross),
+			report.Negative(p.Refunds),
+			report.Amount(p.Net()),
+		)
+		tab.AddRow(row...)
+	}
+
+	return res
+}
+
+// groupLabels returns the dimension cells of the row: the group labels, or the total label in the first one.
+func groupLabels(row sales.Row, dims []sales.Dimension) []report.Cell {
+	res := []report.Cell{}
+	for i, d := range dims {
+		switch {
+		case row.Total != "" && i == 0:
+			res = append(res, report.Text(row.Total))
+		case row.Total != "":
+			res = append(res, report.Text(""))
+		case row.Group.Labels[i] == "":
+			res = append(res, report.Cell{Raw: "", Text: d.None})
+		default:
+			res = append(res, report.Text(row.Group.Labels[i]))
+		}
+	}
+	return res
+}
+
+func metricCell(m sales.Metric, v decimal.Decimal) report.Cell {
+	switch m.Kind {
+	case sales.MetricAmount:
+		return report.Amount(v)
+	case sales.MetricNegative:
+		return report.Negative(v)
+	case sales.MetricPercent:
+		return report.Percent(v, 2)
+	default:
+		return report.Int(int(v.IntPart()))
+	}
+}
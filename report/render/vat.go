@@ -0,0 +1,143 @@
+// Package render lays out the results of the report packages as report results, for the command line and the dashboard.
+package render
+
+import (
+	"fmt"
+
+	"github.com/r0busta/go-shopify-reports/report"
+	"github.com/r0busta/go-shopify-reports/vat"
+	"github.com/shopspring/decimal"
+)
+
+// VATReturn lays out the return with its boxes, and the flat rate of each part of the period for the flat rate scheme.
+func VATReturn(r *vat.Report) *report.Result {
+	res := &report.Result{
+		Currency:    r.Options.Currency,
+		Period:      report.Period{From: r.From, To: r.To},
+		Methodology: r.Methodology,
+	}
+
+	switch r.Scheme {
+	case vat.SchemeFlat:
+		res.Title = "VAT return (flat rate scheme)"
+		tab := res.AddTable("Flat rates", "From", "To", "Turnover", "Flat Rate", "VAT")
+		for _, seg := range r.Segments {
+			tab.AddRow(
+				report.Text(seg.From.Format("2006-01-02")),
+				report.Text(seg.To.Format("2006-01-02")),
+				report.Amount(seg.Turnover),
+				rate(seg.Rate),
+				report.Amount(seg.VAT),
+			)
+		}
+
+		res.AddField("VAT due on sales and other outputs (box 1)", report.Amount(r.Return.VATDueSales))
+		res.AddField("Total VAT due (box 3)", report.Amount(r.Return.TotalVATDue))
+		res.AddField("VAT reclaimed on capital expenditure goods (box 4)", report.Amount(r.Return.VATReclaimedCurrPeriod))
+		res.AddField("Net VAT to pay to HMRC or reclaim (box 5)", report.Amount(r.Return.NetVATDue))
+		res.AddField("Total turnover, including VAT and EC sales (box 6)", report.Amount(r.Return.TotalValueSalesExVAT))
+	default:
+		res.Title = "VAT return (standard scheme)"
+		for _, b := range r.Return.Boxes() {
+			res.AddField(fmt.Sprintf("%s (box %d)", b.Label, b.Number), report.Amount(b.Value))
+		}
+	}
+
+	if len(r.Stores) > 0 {
+		tab := res.AddTable("Stores", "Store", "Box", "Description", "Amount")
+		for _, s := range r.Stores {
+			for _, b := range s.Return.Boxes() {
+				if !storeBox(r.Scheme, b) {
+					continue
+				}
+				tab.AddRow(report.Text(s.Store), report.Int(b.Number), report.Text(b.Label), report.Amount(b.Value))
+			}
+		}
+	}
+
+	return res
+}
+
+// storeBox tells if the box is shown for each store of a consolidated return: the boxes worked out from the orders,
+// of the ones the scheme's return shows.
+func storeBox(scheme string, b vat.Box) bool {
+	if b.Given() {
+		return false
+	}
+	return scheme != vat.SchemeFlat || flatRateBoxes[b.Number]
+}
+
+// flatRateBoxes are the boxes of a flat rate scheme return worked out from the orders.
+var flatRateBoxes = map[int]bool{1: true, 3: true, 6: true}
+
+// VATComparison lays out the return boxes of the period next to the ones of the period compared with,
+// with each store's of a consolidated comparison. The boxes given for the period are left blank for the period
+// compared with, and left out of each store's.
+func VATComparison(c *vat.Comparison) *report.Result {
+	res := &report.Result{
+		Title:       "VAT return",
+		Currency:    c.Options.Currency,
+		Period:      report.Period{From: c.From, To: c.To},
+		Compare:     &report.Period{From: c.PrevFrom, To: c.PrevTo},
+		Methodology: c.Methodology,
+	}
+
+	// Consolidated comparisons compare each store, then all of them
+	sections := []vat.StoreReturn{{Return: c.Return, Prev: c.Prev}}
+	headers := []string{"Box", "Description", res.Period.String(), res.Compare.String(), "Change", "Change %"}
+	if len(c.Stores) > 0 {
+		sections = append(append([]vat.StoreReturn{}, c.Stores...), vat.StoreReturn{Store: "All stores", Return: c.Return, Prev: c.Prev})
+		headers = append([]string{"Store"}, headers...)
+	}
+
+	tab := res.AddTable("", headers...)
+	for n, sec := range sections {
+		store := n < len(c.Stores)
+		prevBoxes := sec.Prev.Boxes()
+		for i, b := range sec.Return.Boxes() {
+			var row []report.Cell
+			switch {
+			case b.Given() && store:
+				continue
+			case b.Given():
+				row = []report.Cell{report.Int(b.Number), report.Text(b.Label), report.Amount(b.Value), {}, {}, {}}
+			default:
+				change, pct := report.Change(b.Value, prevBoxes[i].Value, 2)
+				row = []report.Cell{report.Int(b.Number), report.Text(b.Label), report.Amount(b.Value), report.Amount(prevBoxes[i].Value), change, pct}
+			}
+			if sec.Store != "" {
+				row = append([]report.Cell{report.Text(sec.Store)}, row...)
+			}
+			tab.AddRow(row...)
+		}
+	}
+	return res
+}
+
+// OSSReturn lays out the supplies to each member state at each VAT rate, in euros.
+func OSSReturn(r *vat.OSSReport) *report.Result {
+	res := &report.Result{
+		Title:       "EU One-Stop-Shop VAT return",
+		Period:      report.Period{From: r.From, To: r.To},
+		Methodology: r.Methodology,
+	}
+	tab := res.AddTable("", "Member State", "VAT Rate", "Taxable Amount (EUR)", "VAT (EUR)", "Refunded (EUR)", "Refunded VAT (EUR)", "Net VAT (EUR)")
+	for _, l := range r.Lines {
+		tab.AddRow(
+			report.Text(string(l.Country)),
+			rate(l.Rate),
+			report.Amount(l.TaxableAmount),
+			report.Amount(l.VAT),
+			report.Negative(l.RefundAmount),
+			report.Negative(l.RefundVAT),
+			report.Amount(l.NetVAT()),
+		)
+	}
+	res.AddField("Total OSS VAT payable (EUR)", report.Amount(r.Total()))
+	return res
+}
+
+// rate is a VAT rate percentage, displayed as it is.
+func rate(d decimal.Decimal) report.Cell {
+	return report.Cell{Raw: d, Text: fmt.Sprintf("%s%%", d.String()), Kind: report.KindPercent, Places: 1}
+}
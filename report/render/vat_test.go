@@ -0,0 +1,54 @@
+package render
+
+import (
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-reports/vat"
+	"github.com/shopspring/decimal"
+)
+
+func TestVATComparisonGivenBoxes(t *testing.T) {
+	cur := &vat.Return{
+		VATDueSales:            decimal.RequireFromString("12.00"),
+		TotalVATDue:            decimal.RequireFromString("12.00"),
+		VATReclaimedCurrPeriod: decimal.RequireFromString("5.00"),
+		NetVATDue:              decimal.RequireFromString("7.00"),
+		TotalValueSalesExVAT:   decimal.RequireFromString("120.00"),
+	}
+	prev := &vat.Return{
+		VATDueSales:            decimal.RequireFromString("10.00"),
+		TotalVATDue:            decimal.RequireFromString("10.00"),
+		VATReclaimedCurrPeriod: decimal.RequireFromString("5.00"),
+		NetVATDue:              decimal.RequireFromString("5.00"),
+		TotalValueSalesExVAT:   decimal.RequireFromString("100.00"),
+	}
+	res := VATComparison(&vat.Comparison{
+		From:     time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2020, 4, 30, 23, 59, 59, 0, time.UTC),
+		PrevFrom: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+		PrevTo:   time.Date(2020, 3, 31, 23, 59, 59, 0, time.UTC),
+		Return:   cur,
+		Prev:     prev,
+	})
+
+	rows := res.Tables[0].TextRows()
+	want := map[string][]string{
+		"1": {"1", "", "12.00", "10.00", "2.00", "20.0%"},
+		"4": {"4", "", "5.00", "", "", ""},
+		"5": {"5", "", "7.00", "", "", ""},
+		"7": {"7", "", "0.00", "", "", ""},
+	}
+	for _, row := range rows {
+		w, ok := want[row[0]]
+		if !ok {
+			continue
+		}
+		for i := range w {
+			if i != 1 && row[i] != w[i] {
+				t.Errorf("box %s = %v, want %v", row[0], row, w)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package report
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/shopspring/decimal"
+)
+
+// Result is what a report produces, whatever the output format.
+type Result struct {
+	Title    string
+	Store    string
+	Currency model.CurrencyCode
+
+	Period Period
+	// Compare is the period the report is compared with, if any.
+	Compare *Period
+
+	Tables  []*Table
+	Summary []Field
+	// Details are tables behind the figures, e.g. a per-order breakdown, only written to workbook and PDF exports.
+	Details []*Table
+	// Audit lists the transactions behind the tax figures, written with --audit.
+	Audit *Table
+	// Methodology explains how the figures are computed, for filing records.
+	Methodology []string
+}
+
+type Period struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// Table is a list of rows with a cell per column.
+type Table struct {
+	Name    string
+	Columns []string
+	Rows    [][]Cell
+}
+
+// Field is a named value reported on its own, e.g. a total or a return box.
+type Field struct {
+	Name  string
+	Value Cell
+}
+
+// Kind is what a cell holds, for outputs with typed cells.
+type Kind int
+
+const (
+	KindText Kind = iota
+	KindCount
+	KindAmount
+	KindNegative
+	KindPercent
+	KindDecimal
+)
+
+// Cell is a report value: Raw is what machine-readable outputs write and Text what's displayed.
+// Places are the decimal places Text is displayed with.
+type Cell struct {
+	Raw    interface{}
+	Text   string
+	Kind   Kind
+	Places int32
+}
+
+func Text(s string) Cell {
+	return Cell{Raw: s, Text: s}
+}
+
+func Int(n int) Cell {
+	return Cell{Raw: n, Text: decimal.NewFromInt(int64(n)).String(), Kind: KindCount}
+}
+
+// Amount is a money amount rounded to the cent.
+func Amount(d decimal.Decimal) Cell {
+	return Cell{Raw: d.Round(2), Text: d.StringFixed(2), Kind: KindAmount, Places: 2}
+}
+
+// Negative is an amount taken off, e.g. refunds, displayed in brackets.
+func Negative(d decimal.Decimal) Cell {
+	return Cell{Raw: d.Round(2), Text: "(" + d.StringFixed(2) + ")", Kind: KindNegative, Places: 2}
+}
+
+// Percent is a percentage displayed with the places and a % sign.
+func Percent(d decimal.Decimal, places int32) Cell {
+	return Cell{Raw: d.Round(places), Text: d.StringFixed(places) + "%", Kind: KindPercent, Places: places}
+}
+
+// Decimal is a number displayed with the places.
+func Decimal(d decimal.Decimal, places int32) Cell {
+	return Cell{Raw: d.Round(places), Text: d.StringFixed(places), Kind: KindDecimal, Places: places}
+}
+
+func (t *Table) AddRow(cells ...Cell) {
+	t.Rows = append(t.Rows, cells)
+}
+
+// TextRows returns the displayed text of the cells.
+func (t *Table) TextRows() [][]string {
+	res := [][]string{}
+	for _, row := range t.Rows {
+		r := []string{}
+		for _, c := range row {
+			r = append(r, c.Text)
+		}
+		res = append(res, r)
+	}
+	return res
+}
+
+func (r *Result) AddTable(name string, columns ...string) *Table {
+	t := &Table{Name: name, Columns: columns, Rows: [][]Cell{}}
+	r.Tables = append(r.Tables, t)
+	return t
+}
+
+// AddDetail adds a table only written to workbook and PDF exports.
+func (r *Result) AddDetail(name string, columns ...string) *Table {
+	t := &Table{Name: name, Columns: columns, Rows: [][]Cell{}}
+	r.Details = append(r.Details, t)
+	return t
+}
+
+func (r *Result) AddField(name string, value Cell) {
+	r.Summary = append(r.Summary, Field{Name: name, Value: value})
+}
+
+func (r *Result) MarshalJSON() ([]byte, error) {
+	type field struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	}
+	type table struct {
+		Name    string                   `json:"name,omitempty"`
+		Columns []string                 `json:"columns"`
+		Rows    []map[string]interface{} `json:"rows"`
+	}
+	out := struct {
+		Title       string             `json:"title"`
+		Store       string             `json:"store,omitempty"`
+		Currency    model.CurrencyCode `json:"currency,omitempty"`
+		Period      Period             `json:"period"`
+		Compare     *Period            `json:"compare,omitempty"`
+		Tables      []table            `json:"tables"`
+		Summary     []field            `json:"summary"`
+		Methodology []string           `json:"methodology,omitempty"`
+	}{
+		Title:       r.Title,
+		Store:       r.Store,
+		Currency:    r.Currency,
+		Period:      r.Period,
+		Compare:     r.Compare,
+		Tables:      []table{},
+		Summary:     []field{},
+		Methodology: r.Methodology,
+	}
+
+	for _, t := range r.Tables {
+		tab := table{Name: t.Name, Columns: t.Columns, Rows: []map[string]interface{}{}}
+		for _, row := range t.Rows {
+			obj := map[string]interface{}{}
+			for i, c := range row {
+				if i < len(t.Columns) {
+					obj[t.Columns[i]] = c.Raw
+				}
+			}
+			tab.Rows = append(tab.Rows, obj)
+		}
+		out.Tables = append(out.Tables, tab)
+	}
+	for _, f := range r.Summary {
+		out.Summary = append(out.Summary, field{Name: f.Name, Value: f.Value.Raw})
+	}
+
+	return json.Marshal(out)
+}
+
+// Change returns the change from prev to cur with the places, and the percentage change.
+// The percentage is empty when there's nothing to compare with.
+func Change(cur, prev decimal.Decimal, places int32) (Cell, Cell) {
+	change := cur.Sub(prev)
+	if prev.IsZero() {
+		return Decimal(change, places), Cell{}
+	}
+	return Decimal(change, places), Percent(change.Div(prev.Abs()).Mul(decimal.NewFromInt(100)), 1)
+}
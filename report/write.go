@@ -0,0 +1,200 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/r0busta/go-shopify-reports/utils"
+	"github.com/tomlazar/table"
+)
+
+// Format is how a result is written out.
+type Format string
+
+const (
+	FormatTable    Format = "table"
+	FormatJSON     Format = "json"
+	FormatCSV      Format = "csv"
+	FormatMarkdown Format = "markdown"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case "":
+		return FormatTable, nil
+	case FormatTable, FormatJSON, FormatCSV, FormatMarkdown:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown output format `%s`", s)
+	}
+}
+
+const dateLayout = "2006-01-02"
+
+// Write writes the result in the format.
+func Write(w io.Writer, r *Result, format Format) error {
+	switch format {
+	case "", FormatTable:
+		return writeTable(w, r)
+	case FormatJSON:
+		return utils.WriteFormatedJSON(w, r)
+	case FormatCSV:
+		return writeCSV(w, r)
+	case FormatMarkdown:
+		return writeMarkdown(w, r)
+	default:
+		return fmt.Errorf("unknown output format `%s`", format)
+	}
+}
+
+// Export writes the result to an Excel workbook or a PDF document if the path ends in .xlsx or .pdf,
+// or the result tables to a CSV file otherwise.
+func Export(path string, r *Result) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlsx":
+		err = writeXLSX(out, r)
+		if err != nil {
+			return fmt.Errorf("error exporting xlsx: %w", err)
+		}
+	case ".pdf":
+		err = writePDF(out, r)
+		if err != nil {
+			return fmt.Errorf("error exporting pdf: %w", err)
+		}
+	default:
+		err = writeCSV(out, r)
+		if err != nil {
+			return fmt.Errorf("error exporting csv: %w", err)
+		}
+	}
+	return out.Close()
+}
+
+// ExportsDetails tells if exporting to the path writes the detail tables of a result, i.e. to a workbook or a PDF document.
+func ExportsDetails(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlsx", ".pdf":
+		return true
+	default:
+		return false
+	}
+}
+
+// ExportAudit writes the audit trail of the result to a CSV file.
+func ExportAudit(path string, r *Result) error {
+	if r.Audit == nil {
+		return fmt.Errorf("%s has no audit trail", r.Title)
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	err = writeCSV(out, &Result{Tables: []*Table{r.Audit}})
+	if err != nil {
+		return fmt.Errorf("error exporting audit trail: %w", err)
+	}
+	return out.Close()
+}
+
+func writeTable(w io.Writer, r *Result) error {
+	if r.Compare != nil {
+		fmt.Fprintf(w, "Comparing %s with %s\n", r.Period, *r.Compare)
+	}
+	if r.Currency != "" {
+		fmt.Fprintln(w, "Currency:", r.Currency)
+	}
+	for _, t := range r.Tables {
+		if t.Name != "" && len(r.Tables) > 1 {
+			fmt.Fprintln(w, t.Name)
+		}
+		tab := table.Table{
+			Headers: t.Columns,
+			Rows:    t.TextRows(),
+		}
+		if err := tab.WriteTable(w, nil); err != nil {
+			return err
+		}
+	}
+	for _, f := range r.Summary {
+		fmt.Fprintf(w, "%s: %s\n", f.Name, f.Value.Text)
+	}
+	return nil
+}
+
+// writeCSV writes the tables one after another separated by an empty line, then the summary as name and value rows.
+func writeCSV(w io.Writer, r *Result) error {
+	cw := csv.NewWriter(w)
+	for i, t := range r.Tables {
+		if i > 0 {
+			cw.Write([]string{})
+		}
+		cw.Write(t.Columns)
+		cw.WriteAll(t.TextRows())
+	}
+	if len(r.Summary) > 0 {
+		if len(r.Tables) > 0 {
+			cw.Write([]string{})
+		}
+		for _, f := range r.Summary {
+			cw.Write([]string{f.Name, f.Value.Text})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeMarkdown(w io.Writer, r *Result) error {
+	fmt.Fprintf(w, "# %s\n\n", r.Title)
+	fmt.Fprintf(w, "Period: %s\n", r.Period)
+	if r.Compare != nil {
+		fmt.Fprintf(w, "Compared with: %s\n", *r.Compare)
+	}
+	if r.Currency != "" {
+		fmt.Fprintf(w, "Currency: %s\n", r.Currency)
+	}
+
+	for _, t := range r.Tables {
+		fmt.Fprintln(w)
+		if t.Name != "" {
+			fmt.Fprintf(w, "## %s\n\n", t.Name)
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(escapeMarkdown(t.Columns), " | "))
+		fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(t.Columns)))
+		for _, row := range t.TextRows() {
+			fmt.Fprintf(w, "| %s |\n", strings.Join(escapeMarkdown(row), " | "))
+		}
+	}
+
+	if len(r.Summary) > 0 {
+		fmt.Fprintln(w)
+		for _, f := range r.Summary {
+			fmt.Fprintf(w, "- %s: %s\n", f.Name, f.Value.Text)
+		}
+	}
+	return nil
+}
+
+func escapeMarkdown(cells []string) []string {
+	res := []string{}
+	for _, c := range cells {
+		res = append(res, strings.ReplaceAll(c, "|", `\|`))
+	}
+	return res
+}
+
+func (p Period) String() string {
+	return fmt.Sprintf("%s - %s", p.From.Format(dateLayout), p.To.Format(dateLayout))
+}
@@ -0,0 +1,109 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/shopspring/decimal"
+)
+
+func newResult() *Result {
+	res := &Result{
+		Title:    "Sales by vendor",
+		Currency: model.CurrencyCodeGbp,
+		Period: Period{
+			From: time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2020, 4, 30, 23, 59, 59, 0, time.UTC),
+		},
+	}
+	tab := res.AddTable("", "Vendor", "Orders Count", "Refunded")
+	tab.AddRow(Text("Acme"), Int(2), Negative(decimal.RequireFromString("10.5")))
+	res.AddField("Total", Amount(decimal.RequireFromString("99.999")))
+	return res
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FormatJSON, `{
+    "title": "Sales by vendor",
+    "currency": "GBP",
+    "period": {
+        "from": "2020-04-01T00:00:00Z",
+        "to": "2020-04-30T23:59:59Z"
+    },
+    "tables": [
+        {
+            "columns": [
+                "Vendor",
+                "Orders Count",
+                "Refunded"
+            ],
+            "rows": [
+                {
+                    "Orders Count": 2,
+                    "Refunded": "10.5",
+                    "Vendor": "Acme"
+                }
+            ]
+        }
+    ],
+    "summary": [
+        {
+            "name": "Total",
+            "value": "100"
+        }
+    ]
+}
+`},
+		{FormatCSV, "Vendor,Orders Count,Refunded\nAcme,2,(10.50)\n\nTotal,100.00\n"},
+		{FormatMarkdown, "# Sales by vendor\n\nPeriod: 2020-04-01 - 2020-04-30\nCurrency: GBP\n\n| Vendor | Orders Count | Refunded |\n| --- | --- | --- |\n| Acme | 2 | (10.50) |\n\n- Total: 100.00\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Write(&buf, newResult(), tt.format); err != nil {
+			t.Fatalf("Write(%s), err=%v", tt.format, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("Write(%s) =\n%s\nwant\n%s", tt.format, got, tt.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, newResult(), FormatTable); err != nil {
+		t.Fatalf("Write(table), err=%v", err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "Currency: GBP\n") || !strings.HasSuffix(got, "Total: 100.00\n") {
+		t.Errorf("Write(table) =\n%s", got)
+	}
+}
+
+func TestMarshalJSONStoreAndMethodology(t *testing.T) {
+	res := newResult()
+	res.Store = "acme.myshopify.com"
+	res.Methodology = []string{"Orders are counted when created."}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		t.Fatalf("Marshal(), err=%v", err)
+	}
+	var got struct {
+		Store       string   `json:"store"`
+		Methodology []string `json:"methodology"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal(), err=%v", err)
+	}
+	if got.Store != res.Store {
+		t.Errorf("store = %q, want %q", got.Store, res.Store)
+	}
+	if len(got.Methodology) != 1 || got.Methodology[0] != res.Methodology[0] {
+		t.Errorf("methodology = %v, want %v", got.Methodology, res.Methodology)
+	}
+}
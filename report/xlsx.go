@@ -0,0 +1,297 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	summarySheet  = "Summary"
+	maxSheetName  = 31
+	minColWidth   = 10
+	maxColWidth   = 60
+	headerFill    = "#D9E1F2"
+	sheetNameChar = `[]:*?/\`
+)
+
+// workbook writes a result as an Excel workbook: the period and summary fields on a summary sheet,
+// then a sheet per table and detail table, with numbers in typed cells.
+type workbook struct {
+	f        *excelize.File
+	r        *Result
+	styles   map[string]int
+	titleID  int
+	headerID int
+}
+
+func writeXLSX(w io.Writer, r *Result) error {
+	wb := &workbook{f: excelize.NewFile(), r: r, styles: map[string]int{}}
+	defer wb.f.Close()
+
+	err := wb.init()
+	if err != nil {
+		return err
+	}
+	err = wb.writeSummary()
+	if err != nil {
+		return err
+	}
+
+	names := map[string]bool{summarySheet: true}
+	for _, t := range append(append([]*Table{}, r.Tables...), r.Details...) {
+		name := sheetName(t.Name, r.Title, names)
+		names[name] = true
+		err = wb.writeTable(name, t)
+		if err != nil {
+			return fmt.Errorf("error writing sheet %s: %w", name, err)
+		}
+	}
+
+	_, err = wb.f.WriteTo(w)
+	return err
+}
+
+func (wb *workbook) init() error {
+	err := wb.f.SetSheetName("Sheet1", summarySheet)
+	if err != nil {
+		return err
+	}
+
+	wb.titleID, err = wb.f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	if err != nil {
+		return err
+	}
+	wb.headerID, err = wb.f.NewStyle(&excelize.Style{
+		Font:   &excelize.Font{Bold: true},
+		Fill:   excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{headerFill}},
+		Border: []excelize.Border{{Type: "bottom", Color: "#000000", Style: 1}},
+	})
+	return err
+}
+
+func (wb *workbook) writeSummary() error {
+	rows := [][]Cell{
+		{Text(wb.r.Title)},
+	}
+	if wb.r.Store != "" {
+		rows = append(rows, []Cell{Text("Store"), Text(wb.r.Store)})
+	}
+	rows = append(rows, []Cell{Text("Period"), Text(wb.r.Period.String())})
+	if wb.r.Compare != nil {
+		rows = append(rows, []Cell{Text("Compared with"), Text(wb.r.Compare.String())})
+	}
+	if wb.r.Currency != "" {
+		rows = append(rows, []Cell{Text("Currency"), Text(string(wb.r.Currency))})
+	}
+	if len(wb.r.Summary) > 0 {
+		rows = append(rows, []Cell{})
+		for _, f := range wb.r.Summary {
+			rows = append(rows, []Cell{Text(f.Name), f.Value})
+		}
+	}
+
+	for i, row := range rows {
+		err := wb.writeRow(summarySheet, i+1, row)
+		if err != nil {
+			return err
+		}
+	}
+	err := wb.f.SetCellStyle(summarySheet, "A1", "A1", wb.titleID)
+	if err != nil {
+		return err
+	}
+	return wb.setWidths(summarySheet, rows)
+}
+
+func (wb *workbook) writeTable(sheet string, t *Table) error {
+	_, err := wb.f.NewSheet(sheet)
+	if err != nil {
+		return err
+	}
+
+	header := []Cell{}
+	for _, c := range t.Columns {
+		header = append(header, Text(c))
+	}
+	err = wb.writeRow(sheet, 1, header)
+	if err != nil {
+		return err
+	}
+	if len(t.Columns) > 0 {
+		last, _ := excelize.CoordinatesToCellName(len(t.Columns), 1)
+		err = wb.f.SetCellStyle(sheet, "A1", last, wb.headerID)
+		if err != nil {
+			return err
+		}
+	}
+	err = wb.f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+	if err != nil {
+		return err
+	}
+
+	for i, row := range t.Rows {
+		err = wb.writeRow(sheet, i+2, row)
+		if err != nil {
+			return err
+		}
+	}
+	return wb.setWidths(sheet, append([][]Cell{header}, t.Rows...))
+}
+
+func (wb *workbook) writeRow(sheet string, row int, cells []Cell) error {
+	for i, c := range cells {
+		if c.Raw == nil {
+			continue
+		}
+		axis, err := excelize.CoordinatesToCellName(i+1, row)
+		if err != nil {
+			return err
+		}
+
+		err = wb.f.SetCellValue(sheet, axis, cellValue(c))
+		if err != nil {
+			return err
+		}
+		numFmt := wb.numFmt(c)
+		if numFmt == "" {
+			continue
+		}
+		style, err := wb.style(numFmt)
+		if err != nil {
+			return err
+		}
+		err = wb.f.SetCellStyle(sheet, axis, axis, style)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cellValue returns the number a spreadsheet should hold: negatives are taken off and percentages are fractions.
+func cellValue(c Cell) interface{} {
+	d, ok := c.Raw.(decimal.Decimal)
+	if !ok {
+		return c.Raw
+	}
+	switch c.Kind {
+	case KindNegative:
+		d = d.Abs().Neg()
+	case KindPercent:
+		d = d.Div(decimal.NewFromInt(100))
+	}
+	return d.InexactFloat64()
+}
+
+// numFmt returns the number format of the cell, amounts in the report currency.
+func (wb *workbook) numFmt(c Cell) string {
+	places := ""
+	if c.Places > 0 {
+		places = "." + strings.Repeat("0", int(c.Places))
+	}
+	currency := ""
+	if wb.r.Currency != "" {
+		currency = fmt.Sprintf(`"%s "`, wb.r.Currency)
+	}
+
+	switch c.Kind {
+	case KindCount:
+		return "#,##0"
+	case KindAmount, KindNegative:
+		return fmt.Sprintf("%[1]s#,##0%[2]s;(%[1]s#,##0%[2]s)", currency, places)
+	case KindPercent:
+		return "0" + places + "%"
+	case KindDecimal:
+		return "#,##0" + places
+	default:
+		return ""
+	}
+}
+
+func (wb *workbook) style(numFmt string) (int, error) {
+	if id, ok := wb.styles[numFmt]; ok {
+		return id, nil
+	}
+	id, err := wb.f.NewStyle(&excelize.Style{CustomNumFmt: &numFmt})
+	if err != nil {
+		return 0, err
+	}
+	wb.styles[numFmt] = id
+	return id, nil
+}
+
+// setWidths fits the columns to their longest text, within limits.
+func (wb *workbook) setWidths(sheet string, rows [][]Cell) error {
+	widths := []int{}
+	for _, row := range rows {
+		for i, c := range row {
+			if i >= len(widths) {
+				widths = append(widths, minColWidth)
+			}
+			if n := len(c.Text) + 2; n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	// the title spans the summary sheet
+	if sheet == summarySheet && len(widths) > 0 {
+		widths[0] = minColWidth
+		for _, row := range rows[1:] {
+			if len(row) > 0 && len(row[0].Text)+2 > widths[0] {
+				widths[0] = len(row[0].Text) + 2
+			}
+		}
+	}
+
+	for i, w := range widths {
+		if w > maxColWidth {
+			w = maxColWidth
+		}
+		col, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return err
+		}
+		err = wb.f.SetColWidth(sheet, col, col, float64(w))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sheetName returns a unique valid sheet name for the table, named after the report if it has no name.
+func sheetName(name, title string, taken map[string]bool) string {
+	if name == "" {
+		name = title
+	}
+	if name == "" {
+		name = "Report"
+	}
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(sheetNameChar, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	// Sheet names are limited in characters, not bytes
+	runes := []rune(name)
+	if len(runes) > maxSheetName {
+		runes = runes[:maxSheetName]
+		name = string(runes)
+	}
+
+	res := name
+	for i := 2; taken[res]; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		if len(runes)+len(suffix) > maxSheetName {
+			res = string(runes[:maxSheetName-len(suffix)]) + suffix
+		} else {
+			res = name + suffix
+		}
+	}
+	return res
+}
@@ -0,0 +1,84 @@
+package report
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWriteXLSX(t *testing.T) {
+	res := newResult()
+	orders := res.AddDetail("Orders", "Order", "Turnover (incl. VAT)")
+	orders.AddRow(Text("#1001"), Amount(decimal.RequireFromString("12.34")))
+
+	var buf bytes.Buffer
+	if err := writeXLSX(&buf, res); err != nil {
+		t.Fatalf("writeXLSX(), err=%v", err)
+	}
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("OpenReader(), err=%v", err)
+	}
+	defer f.Close()
+
+	if got, want := f.GetSheetList(), []string{"Summary", "Sales by vendor", "Orders"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSheetList() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		sheet, cell string
+		typ         excelize.CellType
+		raw         string
+		formatted   string
+	}{
+		{"Summary", "A1", excelize.CellTypeSharedString, "Sales by vendor", "Sales by vendor"},
+		{"Summary", "B2", excelize.CellTypeSharedString, "2020-04-01 - 2020-04-30", "2020-04-01 - 2020-04-30"},
+		{"Summary", "B5", excelize.CellTypeUnset, "100", `GBP 100.00`},
+		{"Sales by vendor", "A1", excelize.CellTypeSharedString, "Vendor", "Vendor"},
+		{"Sales by vendor", "B2", excelize.CellTypeUnset, "2", "2"},
+		{"Sales by vendor", "C2", excelize.CellTypeUnset, "-10.5", `(GBP 10.50)`},
+		{"Orders", "B2", excelize.CellTypeUnset, "12.34", `GBP 12.34`},
+	}
+	for _, tt := range tests {
+		typ, err := f.GetCellType(tt.sheet, tt.cell)
+		if err != nil {
+			t.Fatalf("GetCellType(%s, %s), err=%v", tt.sheet, tt.cell, err)
+		}
+		if typ != tt.typ {
+			t.Errorf("GetCellType(%s, %s) = %v, want %v", tt.sheet, tt.cell, typ, tt.typ)
+		}
+		raw, _ := f.GetCellValue(tt.sheet, tt.cell, excelize.Options{RawCellValue: true})
+		if raw != tt.raw {
+			t.Errorf("GetCellValue(%s, %s) raw = %q, want %q", tt.sheet, tt.cell, raw, tt.raw)
+		}
+		formatted, _ := f.GetCellValue(tt.sheet, tt.cell)
+		if formatted != tt.formatted {
+			t.Errorf("GetCellValue(%s, %s) = %q, want %q", tt.sheet, tt.cell, formatted, tt.formatted)
+		}
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	taken := map[string]bool{"Summary": true}
+	tests := []struct {
+		name, want string
+	}{
+		{"", "VAT return (flat rate scheme)"},
+		{"", "VAT return (flat rate schem (2)"},
+		{"Summary", "Summary (2)"},
+		{"EUR/GBP [rates]", "EUR-GBP -rates-"},
+		{"A very long table name that does not fit", "A very long table name that doe"},
+		{"Ventes à l'étranger par société et par pays", "Ventes à l'étranger par société"},
+		{"Ventes à l'étranger par société et par pays", "Ventes à l'étranger par soc (2)"},
+	}
+	for _, tt := range tests {
+		got := sheetName(tt.name, "VAT return (flat rate scheme)", taken)
+		taken[got] = true
+		if got != tt.want {
+			t.Errorf("sheetName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
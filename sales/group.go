@@ -0,0 +1,574 @@
+package sales
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/utils"
+
+	"github.com/shopspring/decimal"
+	"github.com/thoas/go-funk"
+)
+
+// Dimension is what line items are grouped by. A line item can have several values (e.g. tags),
+// its revenue is then split evenly between them.
+type Dimension struct {
+	Name   string
+	Header string
+	// None labels the line items without a value.
+	None   string
+	Values func(o *model.Order, li *model.LineItem) []string
+	// Label displays a value, e.g. the name of a customer grouped by ID. Without it, values are displayed as they are.
+	Label func(o *model.Order, value string) string
+}
+
+var Dimensions = []Dimension{
+	{Name: "tag", Header: "Tag", None: "(untagged)", Values: func(_ *model.Order, li *model.LineItem) []string {
+		return getLineItemTags(li)
+	}},
+	{Name: "vendor", Header: "Vendor", None: "(no vendor)", Values: func(_ *model.Order, li *model.LineItem) []string {
+		return []string{strings.Title(getVendor(li))}
+	}},
+	{Name: "product-type", Header: "Product Type", None: "(no type)", Values: func(_ *model.Order, li *model.LineItem) []string {
+		if li.Product == nil {
+			return nil
+		}
+		return []string{li.Product.ProductType}
+	}},
+	{Name: "product", Header: "Product", None: "(no product)", Values: func(_ *model.Order, li *model.LineItem) []string {
+		_, product, _, _ := getLineItemProduct(li, false)
+		return []string{product}
+	}},
+	{Name: "country", Header: "Country", None: "(no shipping address)", Values: func(o *model.Order, _ *model.LineItem) []string {
+		if code := shop.GetOrderCountryCode(o); code != nil {
+			return []string{string(*code)}
+		}
+		return nil
+	}},
+	{Name: "channel", Header: "Channel", None: "(unknown channel)", Values: func(o *model.Order, _ *model.LineItem) []string {
+		if o.App == nil {
+			return nil
+		}
+		return []string{o.App.Name}
+	}},
+	{Name: "discount-code", Header: "Discount Code", None: "(no discount code)", Values: func(o *model.Order, _ *model.LineItem) []string {
+		res := []string{}
+		for _, c := range o.DiscountCodes {
+			res = append(res, strings.ToUpper(c))
+		}
+		return res
+	}},
+	{Name: "customer", Header: "Customer", None: "(guest)", Values: func(o *model.Order, _ *model.LineItem) []string {
+		if o.Customer == nil {
+			return nil
+		}
+		return []string{o.Customer.ID}
+	}, Label: func(o *model.Order, _ string) string {
+		return o.Customer.DisplayName
+	}},
+}
+
+// DimensionNames lists the dimensions to group sales by.
+func DimensionNames() []string {
+	res := []string{}
+	for _, d := range Dimensions {
+		res = append(res, d.Name)
+	}
+	return res
+}
+
+func ParseDimensions(names []string) ([]Dimension, error) {
+	res := []Dimension{}
+	for _, name := range names {
+		found := false
+		for _, d := range Dimensions {
+			if d.Name == strings.ToLower(strings.TrimSpace(name)) {
+				res = append(res, d)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown dimension `%s`, expected one of %s", name, strings.Join(DimensionNames(), ", "))
+		}
+	}
+	return res, nil
+}
+
+// Group is the sales of the line items sharing the same dimension values.
+type Group struct {
+	Keys []string
+	// Labels display the keys
+	Labels []string
+
+	OrdersCount       int
+	Quantity          int
+	FulfilledQuantity int
+	Revenue           decimal.Decimal
+	Refunded          decimal.Decimal
+
+	// orders and lines are the ones counted towards the orders and units, so adding up groups counts them once
+	orders map[*model.Order]bool
+	lines  map[*model.LineItem]bool
+	// total is set on the total of a section of groups, which has no keys
+	total bool
+}
+
+func (g *Group) RefundRatio() decimal.Decimal {
+	if g.Revenue.IsZero() {
+		return decimal.Zero
+	}
+	return g.Refunded.Abs().Div(g.Revenue)
+}
+
+// MetricKind is how the values of a metric are displayed.
+type MetricKind int
+
+const (
+	MetricCount MetricKind = iota
+	MetricAmount
+	// MetricNegative is an amount taken off, e.g. refunds.
+	MetricNegative
+	MetricPercent
+)
+
+// Metric is a report column calculated from a group.
+type Metric struct {
+	Name   string
+	Header string
+	Value  func(g *Group) decimal.Decimal
+	Kind   MetricKind
+	// Places are the decimal places of the change when comparing periods.
+	Places int32
+}
+
+var Metrics = []Metric{
+	{Name: "orders", Header: "Orders Count", Kind: MetricCount, Value: func(g *Group) decimal.Decimal {
+		return decimal.NewFromInt(int64(g.OrdersCount))
+	}},
+	{Name: "units", Header: "Items Ordered", Kind: MetricCount, Value: func(g *Group) decimal.Decimal {
+		return decimal.NewFromInt(int64(g.Quantity))
+	}},
+	{Name: "fulfilled", Header: "Items Fulfilled", Kind: MetricCount, Value: func(g *Group) decimal.Decimal {
+		return decimal.NewFromInt(int64(g.FulfilledQuantity))
+	}},
+	{Name: "revenue", Header: "Revenue", Kind: MetricAmount, Places: 2, Value: func(g *Group) decimal.Decimal {
+		return g.Revenue
+	}},
+	{Name: "refunded", Header: "Refunded", Kind: MetricNegative, Places: 2, Value: func(g *Group) decimal.Decimal {
+		return g.Refunded
+	}},
+	{Name: "net", Header: "Net Revenue", Kind: MetricAmount, Places: 2, Value: func(g *Group) decimal.Decimal {
+		return g.Revenue.Sub(g.Refunded)
+	}},
+	{Name: "refund-ratio", Header: "Refund Ratio", Kind: MetricPercent, Places: 2, Value: func(g *Group) decimal.Decimal {
+		return g.RefundRatio().Mul(decimal.NewFromInt(100))
+	}},
+}
+
+// DefaultMetrics are the columns of the tag and vendor reports.
+var DefaultMetrics = []string{"orders", "fulfilled", "revenue", "refunded", "refund-ratio"}
+
+// IntervalMetrics are the columns of the reports by interval.
+var IntervalMetrics = []string{"orders", "units", "revenue", "refunded", "net"}
+
+// MetricNames lists the metrics a report can show.
+func MetricNames() []string {
+	res := []string{}
+	for _, m := range Metrics {
+		res = append(res, m.Name)
+	}
+	return res
+}
+
+func ParseMetrics(names []string) ([]Metric, error) {
+	if len(names) == 0 {
+		names = DefaultMetrics
+	}
+
+	res := []Metric{}
+	for _, name := range names {
+		found := false
+		for _, m := range Metrics {
+			if m.Name == strings.ToLower(strings.TrimSpace(name)) {
+				res = append(res, m)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown metric `%s`, expected one of %s", name, strings.Join(MetricNames(), ", "))
+		}
+	}
+	return res, nil
+}
+
+// GroupBy adds up the line item revenue recognised in the period by the dimension values.
+// All the orders count towards the orders and units, unless byActivity is set for bucketing by interval:
+// then only the orders with sales in the period count, so an order isn't counted again in the interval
+// it's refunded in, and the orders without sales or refunds in the period are left out.
+// Groups are sorted by their keys.
+func GroupBy(orders []*model.Order, dims []Dimension, from, to time.Time, opts shop.CalcOptions, byActivity bool) ([]*Group, error) {
+	groups := map[string]*Group{}
+
+	for _, o := range orders {
+		lines, err := shop.CalcLineItemRevenue(o, from, to, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error getting line item revenue: %w", err)
+		}
+
+		sold, refunded := !byActivity, false
+		for _, line := range lines {
+			sold = sold || !line.Sales.IsZero()
+			refunded = refunded || !line.Refunds.IsZero()
+		}
+		if !sold && !refunded {
+			continue
+		}
+
+		for _, line := range lines {
+			combinations, labels := dimensionValues(o, line.LineItem, dims)
+			// A line in several groups is split evenly between them so the groups add up to the turnover.
+			n := decimal.NewFromInt(int64(len(combinations)))
+
+			for i, keys := range combinations {
+				id := strings.Join(keys, "\x00")
+				g, ok := groups[id]
+				if !ok {
+					g = &Group{Keys: keys, Labels: labels[i], orders: map[*model.Order]bool{}, lines: map[*model.LineItem]bool{}}
+					groups[id] = g
+				}
+				if sold {
+					if !g.orders[o] {
+						g.OrdersCount++
+						g.orders[o] = true
+					}
+					g.lines[line.LineItem] = true
+					g.Quantity += line.LineItem.Quantity
+					g.FulfilledQuantity += line.LineItem.Quantity - line.LineItem.UnfulfilledQuantity
+				}
+				g.Revenue = g.Revenue.Add(line.Sales.Div(n))
+				g.Refunded = g.Refunded.Add(line.Refunds.Div(n))
+			}
+		}
+	}
+
+	res := make([]*Group, 0, len(groups))
+	for _, g := range groups {
+		res = append(res, g)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return lessGroup(res[i], res[j])
+	})
+	return res, nil
+}
+
+// lessGroup sorts groups by their labels, then by their keys for the groups with the same labels.
+func lessGroup(a, b *Group) bool {
+	la, lb := strings.Join(a.Labels, "\x00"), strings.Join(b.Labels, "\x00")
+	if la != lb {
+		return la < lb
+	}
+	return strings.Join(a.Keys, "\x00") < strings.Join(b.Keys, "\x00")
+}
+
+// dimensionValues returns every combination of the line item values across the dimensions, and their labels.
+func dimensionValues(o *model.Order, li *model.LineItem, dims []Dimension) ([][]string, [][]string) {
+	res, labels := [][]string{{}}, [][]string{{}}
+	for _, d := range dims {
+		values := funk.UniqString(d.Values(o, li))
+		if len(values) == 0 {
+			values = []string{""}
+		}
+
+		next, nextLabels := [][]string{}, [][]string{}
+		for i, keys := range res {
+			for _, v := range values {
+				label := v
+				if d.Label != nil && v != "" {
+					label = d.Label(o, v)
+				}
+				next = append(next, append(append([]string{}, keys...), v))
+				nextLabels = append(nextLabels, append(append([]string{}, labels[i]...), label))
+			}
+		}
+		res, labels = next, nextLabels
+	}
+	return res, labels
+}
+
+// By groups the sales in the period grouped by the dimensions, one level per dimension.
+// With an interval, the groups are reported for each day, week, month or quarter of the period by transaction date.
+func By(ctx context.Context, service shop.OrderService, by []string, metrics []string, interval utils.Interval, period []string, compare []string, useCached bool, opts shop.CalcOptions) (*Report, error) {
+	dims, err := ParseDimensions(by)
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) == 0 && interval != "" {
+		metrics = IntervalMetrics
+	}
+	cols, err := ParseMetrics(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupReport(ctx, "Sales", service, dims, cols, nil, interval, period, compare, useCached, opts)
+}
+
+// Report is the sales groups of a period, for each interval bucket or compared with another period.
+type Report struct {
+	Title string
+	From  time.Time
+	To    time.Time
+	// Options are the calculation options the sales are in, with the report currency resolved.
+	Options shop.CalcOptions
+	Orders  []*model.Order
+
+	Dimensions []Dimension
+	Metrics    []Metric
+	Interval   utils.Interval
+
+	Compare  bool
+	PrevFrom time.Time
+	PrevTo   time.Time
+
+	// ByStore is set on reports consolidated across stores, with a section of rows per store and one of all the stores.
+	ByStore bool
+	Rows    []Row
+}
+
+// Row is a group of a report, or the total of a section of groups.
+type Row struct {
+	// Period labels the interval bucket of the group, when grouping by interval.
+	Period string
+	// Store is the store of the section, for reports consolidated across stores.
+	Store string
+	// Total labels the total of a section, e.g. Subtotal, and is empty for the groups.
+	Total string
+
+	Group *Group
+	// Prev is the group in the period compared with, when comparing periods.
+	Prev *Group
+}
+
+// groupReport groups the sales of each interval bucket, or of the period compared with another one if compare is set.
+func groupReport(ctx context.Context, title string, service shop.OrderService, dims []Dimension, metrics []Metric, keep func(g *Group) bool, interval utils.Interval, period []string, compare []string, useCached bool, opts shop.CalcOptions) (*Report, error) {
+	from, to, err := utils.ParsePeriodIn(period, opts.Location)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing period dates: %w", err)
+	}
+	if len(compare) > 0 && interval != "" {
+		return nil, fmt.Errorf("comparing periods by interval isn't supported")
+	}
+
+	orders, err := service.ListCreatedBetween(ctx, *from, *to, useCached)
+	if err != nil {
+		return nil, fmt.Errorf("error getting orders: %w", err)
+	}
+	shop.Log.Printf("Found %d orders", len(orders))
+
+	res := &Report{
+		Title:      title,
+		From:       *from,
+		To:         *to,
+		Orders:     orders,
+		Dimensions: dims,
+		Metrics:    metrics,
+		Interval:   interval,
+	}
+
+	if len(compare) > 0 {
+		prevFrom, prevTo, err := utils.ParseComparePeriod(compare, *from, *to)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing period to compare with: %w", err)
+		}
+		prevOrders, err := service.ListCreatedBetween(ctx, *prevFrom, *prevTo, useCached)
+		if err != nil {
+			return nil, fmt.Errorf("error getting orders: %w", err)
+		}
+		shop.Log.Printf("Found %d orders to compare with", len(prevOrders))
+
+		res.Options, err = shop.ResolveCurrency(append(append([]*model.Order{}, orders...), prevOrders...), opts)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving report currency: %w", err)
+		}
+		res.Compare, res.PrevFrom, res.PrevTo = true, *prevFrom, *prevTo
+
+		secs := sections(service, orders, prevOrders)
+		res.ByStore = secs[0].store != ""
+		for _, sec := range secs {
+			pairs, err := pairGroups(sec, dims, keep, *from, *to, *prevFrom, *prevTo, res.Options)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range pairs {
+				res.Rows = append(res.Rows, sec.row(p.cur, p.prev, ""))
+			}
+		}
+		return res, nil
+	}
+
+	res.Options, err = shop.ResolveCurrency(orders, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving report currency: %w", err)
+	}
+
+	secs := sections(service, orders, nil)
+	res.ByStore = secs[0].store != ""
+	for _, b := range utils.SplitPeriod(*from, *to, interval) {
+		label := ""
+		if interval != "" {
+			label = b.Label
+		}
+		for _, sec := range secs {
+			groups, err := GroupBy(sec.orders, dims, b.From, b.To, res.Options, interval != "")
+			if err != nil {
+				return nil, fmt.Errorf("error grouping sales: %w", err)
+			}
+			groups = keepGroups(groups, keep)
+			if sec.total != "" && len(dims) > 0 {
+				groups = append(groups, totalOf(groups))
+			}
+
+			for _, g := range groups {
+				res.Rows = append(res.Rows, sec.row(g, nil, label))
+			}
+		}
+	}
+	return res, nil
+}
+
+type groupPair struct {
+	cur, prev *Group
+}
+
+// pairGroups matches the groups of the section in both periods, sorted by their keys, followed by the section total
+// of the groups kept if it has one.
+func pairGroups(sec section, dims []Dimension, keep func(g *Group) bool, from, to, prevFrom, prevTo time.Time, opts shop.CalcOptions) ([]*groupPair, error) {
+	groups, err := GroupBy(sec.orders, dims, from, to, opts, false)
+	if err != nil {
+		return nil, fmt.Errorf("error grouping sales: %w", err)
+	}
+	prevGroups, err := GroupBy(sec.prevOrders, dims, prevFrom, prevTo, opts, false)
+	if err != nil {
+		return nil, fmt.Errorf("error grouping sales: %w", err)
+	}
+
+	pairs := map[string]*groupPair{}
+	ids := []string{}
+	add := func(g *Group, isPrev bool) {
+		id := strings.Join(g.Keys, "\x00")
+		p, ok := pairs[id]
+		if !ok {
+			p = &groupPair{cur: &Group{Keys: g.Keys, Labels: g.Labels}, prev: &Group{Keys: g.Keys, Labels: g.Labels}}
+			pairs[id] = p
+			ids = append(ids, id)
+		}
+		if isPrev {
+			p.prev = g
+		} else {
+			p.cur = g
+		}
+	}
+	for _, g := range groups {
+		add(g, false)
+	}
+	for _, g := range prevGroups {
+		add(g, true)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return lessGroup(pairs[ids[i]].cur, pairs[ids[j]].cur)
+	})
+
+	res := []*groupPair{}
+	for _, id := range ids {
+		p := pairs[id]
+		if keep != nil && !keep(p.cur) {
+			continue
+		}
+		res = append(res, p)
+	}
+
+	if sec.total != "" && len(dims) > 0 {
+		cur, prev := []*Group{}, []*Group{}
+		for _, p := range res {
+			cur = append(cur, p.cur)
+			prev = append(prev, p.prev)
+		}
+		res = append(res, &groupPair{cur: totalOf(cur), prev: totalOf(prev)})
+	}
+	return res, nil
+}
+
+// section is a run of rows of a group report. Consolidated reports across stores have a section with each store's
+// groups and subtotal, then one with the groups of all the stores and the total. Other reports have one section of all the groups.
+type section struct {
+	store              string
+	orders, prevOrders []*model.Order
+	// total labels the row adding up the section's groups, if it has one
+	total string
+}
+
+func sections(service shop.OrderService, orders, prevOrders []*model.Order) []section {
+	stores, ok := service.(*shop.MultiStoreService)
+	if !ok {
+		return []section{{orders: orders, prevOrders: prevOrders}}
+	}
+
+	res := []section{}
+	byStore, prevByStore := stores.ByStore(orders), stores.ByStore(prevOrders)
+	for i, name := range stores.Stores() {
+		res = append(res, section{store: name, orders: byStore[i], prevOrders: prevByStore[i], total: "Subtotal"})
+	}
+	return append(res, section{store: "All stores", orders: orders, prevOrders: prevOrders, total: "Total"})
+}
+
+// row returns the row of the group in the section, in the interval bucket labelled period if any.
+func (sec section) row(g, prev *Group, period string) Row {
+	res := Row{Period: period, Store: sec.store, Group: g, Prev: prev}
+	if g.total {
+		res.Total = sec.total
+	}
+	return res
+}
+
+// keepGroups returns the groups to report, all of them if keep is nil.
+func keepGroups(groups []*Group, keep func(g *Group) bool) []*Group {
+	if keep == nil {
+		return groups
+	}
+	res := []*Group{}
+	for _, g := range groups {
+		if keep(g) {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+// totalOf adds up the groups as one. The orders and units in several of the groups are counted once.
+func totalOf(groups []*Group) *Group {
+	res := &Group{total: true}
+	orders, lines := map[*model.Order]bool{}, map[*model.LineItem]bool{}
+	for _, g := range groups {
+		for o := range g.orders {
+			orders[o] = true
+		}
+		for li := range g.lines {
+			if !lines[li] {
+				lines[li] = true
+				res.Quantity += li.Quantity
+				res.FulfilledQuantity += li.Quantity - li.UnfulfilledQuantity
+			}
+		}
+		res.Revenue = res.Revenue.Add(g.Revenue)
+		res.Refunded = res.Refunded.Add(g.Refunded)
+	}
+	res.OrdersCount = len(orders)
+	return res
+}
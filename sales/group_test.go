@@ -0,0 +1,201 @@
+package sales
+
+import (
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v4"
+)
+
+func newLineItem(vendor string, tags []string, total string) model.LineItemEdge {
+	return model.LineItemEdge{
+		Node: &model.LineItem{
+			Vendor:          &vendor,
+			Product:         &model.Product{Tags: tags},
+			Quantity:        1,
+			CurrentQuantity: 1,
+			DiscountedTotalSet: &model.MoneyBag{
+				ShopMoney: &model.MoneyV2{
+					Amount: null.StringFrom(total),
+				},
+			},
+		},
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	from := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 4, 30, 23, 59, 59, 0, time.UTC)
+
+	orders := []*model.Order{
+		{
+			CreatedAt: time.Date(2020, 4, 10, 9, 0, 0, 0, time.UTC).Format(shop.ISO8601Layout),
+			Transactions: []model.OrderTransaction{
+				{
+					ProcessedAt: model.NewString(time.Date(2020, 4, 10, 9, 0, 0, 0, time.UTC).Format(shop.ISO8601Layout)),
+					Kind:        model.OrderTransactionKindSale,
+					Status:      model.OrderTransactionStatusSuccess,
+					AmountSet: &model.MoneyBag{
+						ShopMoney: &model.MoneyV2{
+							Amount: null.StringFrom("30.00"),
+						},
+					},
+				},
+			},
+			LineItems: &model.LineItemConnection{
+				Edges: []model.LineItemEdge{
+					newLineItem("acme", []string{"Jeans", "Sale"}, "20.00"),
+					newLineItem("globex", []string{"Jeans"}, "10.00"),
+				},
+			},
+		},
+	}
+
+	dims, err := ParseDimensions([]string{"tag", "vendor"})
+	if err != nil {
+		t.Fatalf("ParseDimensions(), err=%v", err)
+	}
+	got, err := GroupBy(orders, dims, from, to, shop.CalcOptions{}, false)
+	if err != nil {
+		t.Fatalf("GroupBy(), err=%v", err)
+	}
+
+	want := []struct {
+		keys    []string
+		revenue string
+	}{
+		{[]string{"jeans", "Acme"}, "10.00"},
+		{[]string{"jeans", "Globex"}, "10.00"},
+		{[]string{"sale", "Acme"}, "10.00"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GroupBy() returned %d groups, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Keys[0] != w.keys[0] || got[i].Keys[1] != w.keys[1] {
+			t.Errorf("GroupBy() group %d keys = %v, want %v", i, got[i].Keys, w.keys)
+		}
+		if !got[i].Revenue.Equal(decimal.RequireFromString(w.revenue)) {
+			t.Errorf("GroupBy() group %d revenue = %s, want %s", i, got[i].Revenue, w.revenue)
+		}
+		if got[i].OrdersCount != 1 {
+			t.Errorf("GroupBy() group %d orders = %d, want 1", i, got[i].OrdersCount)
+		}
+	}
+}
+
+func TestGroupByCustomer(t *testing.T) {
+	from := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 4, 30, 23, 59, 59, 0, time.UTC)
+
+	newOrder := func(customerID, name, total string) *model.Order {
+		return &model.Order{
+			CreatedAt: time.Date(2020, 4, 10, 9, 0, 0, 0, time.UTC).Format(shop.ISO8601Layout),
+			Customer:  &model.Customer{ID: customerID, DisplayName: name},
+			Transactions: []model.OrderTransaction{
+				{
+					ProcessedAt: model.NewString(time.Date(2020, 4, 10, 9, 0, 0, 0, time.UTC).Format(shop.ISO8601Layout)),
+					Kind:        model.OrderTransactionKindSale,
+					Status:      model.OrderTransactionStatusSuccess,
+					AmountSet:   &model.MoneyBag{ShopMoney: &model.MoneyV2{Amount: null.StringFrom(total)}},
+				},
+			},
+			LineItems: &model.LineItemConnection{
+				Edges: []model.LineItemEdge{newLineItem("acme", nil, total)},
+			},
+		}
+	}
+	orders := []*model.Order{
+		newOrder("gid://shopify/Customer/2", "Jane Smith", "10.00"),
+		newOrder("gid://shopify/Customer/1", "Jane Smith", "20.00"),
+		newOrder("gid://shopify/Customer/1", "Jane Smith", "5.00"),
+	}
+
+	dims, err := ParseDimensions([]string{"customer"})
+	if err != nil {
+		t.Fatalf("ParseDimensions(), err=%v", err)
+	}
+	got, err := GroupBy(orders, dims, from, to, shop.CalcOptions{}, false)
+	if err != nil {
+		t.Fatalf("GroupBy(), err=%v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("GroupBy() returned %d groups, want one per customer", len(got))
+	}
+	for i, want := range []struct {
+		key     string
+		revenue string
+	}{
+		{"gid://shopify/Customer/1", "25.00"},
+		{"gid://shopify/Customer/2", "10.00"},
+	} {
+		if got[i].Keys[0] != want.key || got[i].Labels[0] != "Jane Smith" {
+			t.Errorf("GroupBy() group %d = %v %v, want %s labelled Jane Smith", i, got[i].Keys, got[i].Labels, want.key)
+		}
+		if !got[i].Revenue.Equal(decimal.RequireFromString(want.revenue)) {
+			t.Errorf("GroupBy() group %d revenue = %s, want %s", i, got[i].Revenue, want.revenue)
+		}
+	}
+}
+
+func TestGroupByActivity(t *testing.T) {
+	newTransaction := func(kind model.OrderTransactionKind, amount string, at time.Time) model.OrderTransaction {
+		return model.OrderTransaction{
+			ProcessedAt: model.NewString(at.Format(shop.ISO8601Layout)),
+			Kind:        kind,
+			Status:      model.OrderTransactionStatusSuccess,
+			AmountSet:   &model.MoneyBag{ShopMoney: &model.MoneyV2{Amount: null.StringFrom(amount)}},
+		}
+	}
+	// An order placed on the 1st and refunded on the 2nd, and an order without payments.
+	orders := []*model.Order{
+		{
+			CreatedAt: time.Date(2020, 4, 1, 9, 0, 0, 0, time.UTC).Format(shop.ISO8601Layout),
+			Transactions: []model.OrderTransaction{
+				newTransaction(model.OrderTransactionKindSale, "20.00", time.Date(2020, 4, 1, 9, 0, 0, 0, time.UTC)),
+				newTransaction(model.OrderTransactionKindRefund, "20.00", time.Date(2020, 4, 2, 9, 0, 0, 0, time.UTC)),
+			},
+			LineItems: &model.LineItemConnection{Edges: []model.LineItemEdge{newLineItem("acme", nil, "20.00")}},
+		},
+		{
+			CreatedAt: time.Date(2020, 4, 2, 9, 0, 0, 0, time.UTC).Format(shop.ISO8601Layout),
+			LineItems: &model.LineItemConnection{Edges: []model.LineItemEdge{newLineItem("acme", nil, "10.00")}},
+		},
+	}
+	dims, err := ParseDimensions([]string{"vendor"})
+	if err != nil {
+		t.Fatalf("ParseDimensions(), err=%v", err)
+	}
+
+	tests := []struct {
+		name       string
+		day        int
+		byActivity bool
+		orders     int
+		refunded   string
+	}{
+		{"period", 2, false, 2, "20.00"},
+		{"interval sold", 1, true, 1, "0"},
+		{"interval refunded", 2, true, 0, "20.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := time.Date(2020, 4, tt.day, 0, 0, 0, 0, time.UTC)
+			to := from.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			got, err := GroupBy(orders, dims, from, to, shop.CalcOptions{}, tt.byActivity)
+			if err != nil {
+				t.Fatalf("GroupBy(), err=%v", err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("GroupBy() returned %d groups, want 1", len(got))
+			}
+			if got[0].OrdersCount != tt.orders || !got[0].Refunded.Equal(decimal.RequireFromString(tt.refunded)) {
+				t.Errorf("GroupBy() = %d orders, %s refunded, want %d, %s", got[0].OrdersCount, got[0].Refunded, tt.orders, tt.refunded)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package sales
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+// ProductSales is the units and revenue of a product, or of a variant SKU.
+type ProductSales struct {
+	Product string
+	Variant string
+	Sku     string
+
+	Ordered   int
+	Fulfilled int
+	Refunded  int
+	Gross     decimal.Decimal
+	Refunds   decimal.Decimal
+}
+
+func (p *ProductSales) Net() decimal.Decimal {
+	return p.Gross.Sub(p.Refunds)
+}
+
+// ProductReport is the sales of a period per product, or per variant SKU if ByVariant is set, sorted by product and variant.
+type ProductReport struct {
+	From time.Time
+	To   time.Time
+	// Options are the calculation options the sales are in, with the report currency resolved.
+	Options shop.CalcOptions
+	Orders  []*model.Order
+
+	ByVariant bool
+	Products  []ProductSales
+}
+
+// ByProduct adds up the units and revenue of the line items per product, or per variant SKU when byVariant is set.
+// Revenue is recognised in the period as in the other sales reports. Units ordered and fulfilled count for the orders
+// with sales in the period, units refunded for the refunds made in it.
+func ByProduct(ctx context.Context, service shop.OrderService, period []string, useCached bool, opts shop.CalcOptions, byVariant bool) (*ProductReport, error) {
+	from, to, err := utils.ParsePeriodIn(period, opts.Location)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing period dates: %w", err)
+	}
+
+	orders, err := service.ListCreatedBetween(ctx, *from, *to, useCached)
+	if err != nil {
+		return nil, fmt.Errorf("error getting orders: %w", err)
+	}
+	shop.Log.Printf("Found %d orders", len(orders))
+
+	opts, err = shop.ResolveCurrency(orders, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving report currency: %w", err)
+	}
+
+	stats := map[string]*ProductSales{}
+
+	for _, o := range orders {
+		lines, err := shop.CalcLineItemRevenue(o, *from, *to, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error getting line item revenue: %w", err)
+		}
+		sold := false
+		for _, line := range lines {
+			sold = sold || !line.Sales.IsZero()
+		}
+
+		for _, line := range lines {
+			if !sold && line.Refunds.IsZero() && line.RefundedQuantity == 0 {
+				continue
+			}
+
+			li := line.LineItem
+			key, product, variant, sku := getLineItemProduct(li, byVariant)
+			if _, ok := stats[key]; !ok {
+				stats[key] = &ProductSales{Product: product, Variant: variant, Sku: sku}
+			}
+			stat := stats[key]
+
+			if sold {
+				stat.Ordered += li.Quantity
+				stat.Fulfilled += li.Quantity - li.UnfulfilledQuantity
+			}
+			stat.Refunded += line.RefundedQuantity
+			stat.Gross = stat.Gross.Add(line.Sales)
+			stat.Refunds = stat.Refunds.Add(line.Refunds)
+		}
+	}
+
+	keys := make([]string, 0, len(stats))
+	for k := range stats {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := stats[keys[i]], stats[keys[j]]
+		if a.Product != b.Product {
+			return a.Product < b.Product
+		}
+		return a.Variant < b.Variant
+	})
+
+	res := &ProductReport{
+		From:      *from,
+		To:        *to,
+		Options:   opts,
+		Orders:    orders,
+		ByVariant: byVariant,
+		Products:  []ProductSales{},
+	}
+	for _, k := range keys {
+		res.Products = append(res.Products, *stats[k])
+	}
+	return res, nil
+}
+
+// getLineItemProduct returns the key to group the line item by and its product, variant and SKU names.
+// Line items of deleted products fall back to the title and SKU they were ordered with.
+func getLineItemProduct(li *model.LineItem, byVariant bool) (key, product, variant, sku string) {
+	product = li.Title
+	key = "title:" + li.Title
+	if li.Product != nil {
+		if li.Product.Title != "" {
+			product = li.Product.Title
+		}
+		key = li.Product.ID
+	}
+	if !byVariant {
+		return key, product, "", ""
+	}
+
+	if li.Sku != nil {
+		sku = *li.Sku
+	}
+	if li.VariantTitle != nil {
+		variant = *li.VariantTitle
+	}
+	if li.Variant != nil {
+		key = li.Variant.ID
+		variant = li.Variant.Title
+		if li.Variant.Sku != nil && *li.Variant.Sku != "" {
+			sku = *li.Variant.Sku
+		}
+	} else {
+		key += "/" + sku
+	}
+	return key, product, variant, sku
+}
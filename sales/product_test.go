@@ -0,0 +1,104 @@
+package sales
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/shop/shoptest"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestByProduct(t *testing.T) {
+	newTransaction := func(kind model.OrderTransactionKind, amount string, at time.Time) model.OrderTransaction {
+		return model.OrderTransaction{
+			ProcessedAt: model.NewString(at.Format(shop.ISO8601Layout)),
+			Kind:        kind,
+			Status:      model.OrderTransactionStatusSuccess,
+			AmountSet:   &model.MoneyBag{ShopMoney: &model.MoneyV2{Amount: null.StringFrom(amount)}},
+		}
+	}
+	newOrder := func(createdAt, updatedAt time.Time, product string, transactions ...model.OrderTransaction) *model.Order {
+		li := newLineItem("acme", nil, "30.00")
+		li.Node.ID = "gid://shopify/LineItem/" + product
+		li.Node.Title = product
+		li.Node.Product = nil
+		return &model.Order{
+			CreatedAt:    createdAt.Format(shop.ISO8601Layout),
+			UpdatedAt:    updatedAt.Format(shop.ISO8601Layout),
+			ProcessedAt:  createdAt.Format(shop.ISO8601Layout),
+			Transactions: transactions,
+			LineItems:    &model.LineItemConnection{Edges: []model.LineItemEdge{li}},
+		}
+	}
+
+	march := time.Date(2020, 3, 30, 10, 0, 0, 0, time.UTC)
+	april := time.Date(2020, 4, 2, 10, 0, 0, 0, time.UTC)
+	// A March order paid in April, a March order refunded in April and an April order.
+	paidLater := newOrder(march, april, "Tee", newTransaction(model.OrderTransactionKindSale, "30.00", april))
+	refunded := newOrder(march, april, "Jeans",
+		newTransaction(model.OrderTransactionKindSale, "30.00", march),
+		newTransaction(model.OrderTransactionKindRefund, "30.00", april),
+	)
+	refunded.Refunds = []model.Refund{{
+		CreatedAt:        model.NewString(april.Format(shop.ISO8601Layout)),
+		TotalRefundedSet: &model.MoneyBag{ShopMoney: &model.MoneyV2{Amount: null.StringFrom("30.00")}},
+		RefundLineItems: &model.RefundLineItemConnection{Edges: []model.RefundLineItemEdge{{Node: &model.RefundLineItem{
+			LineItem:    &model.LineItem{ID: "gid://shopify/LineItem/Jeans"},
+			Quantity:    1,
+			SubtotalSet: &model.MoneyBag{ShopMoney: &model.MoneyV2{Amount: null.StringFrom("30.00")}},
+		}}}},
+	}}
+	placed := newOrder(april, april, "Hat", newTransaction(model.OrderTransactionKindSale, "30.00", april))
+	orders := &shoptest.OrderService{Orders: []*model.Order{paidLater, refunded, placed}}
+
+	tests := []struct {
+		basis shop.Basis
+		want  [][]string
+	}{
+		{shop.BasisCash, [][]string{
+			{"Hat", "1", "1", "0", "30.00", "0.00", "30.00"},
+			{"Jeans", "0", "0", "1", "0.00", "30.00", "-30.00"},
+			{"Tee", "1", "1", "0", "30.00", "0.00", "30.00"},
+		}},
+		{shop.BasisAccrual, [][]string{
+			{"Hat", "1", "1", "0", "30.00", "0.00", "30.00"},
+			{"Jeans", "0", "0", "1", "0.00", "30.00", "-30.00"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.basis), func(t *testing.T) {
+			opts := shop.CalcOptions{Basis: tt.basis, Location: time.UTC}
+			res, err := ByProduct(context.Background(), orders, []string{"2020-04"}, false, opts, false)
+			if err != nil {
+				t.Fatalf("ByProduct(), err=%v", err)
+			}
+			got := [][]string{}
+			for _, p := range res.Products {
+				got = append(got, []string{
+					p.Product,
+					strconv.Itoa(p.Ordered),
+					strconv.Itoa(p.Fulfilled),
+					strconv.Itoa(p.Refunded),
+					p.Gross.StringFixed(2),
+					p.Refunds.StringFixed(2),
+					p.Net().StringFixed(2),
+				})
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ByProduct() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				for j := range tt.want[i] {
+					if got[i][j] != tt.want[i][j] {
+						t.Errorf("ByProduct() row %d = %v, want %v", i, got[i], tt.want[i])
+						break
+					}
+				}
+			}
+		})
+	}
+}
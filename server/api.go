@@ -0,0 +1,88 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/utils"
+)
+
+const apiPrefix = "/reports"
+
+// apiReport describes a report of the API, its query parameters are the period ones plus Params.
+type apiReport struct {
+	Path    string  `json:"path"`
+	Title   string  `json:"title"`
+	Compare bool    `json:"compare"`
+	Params  []field `json:"params"`
+}
+
+var periodParams = []field{
+	{Name: "from", Label: "Period start date", Help: "YYYY-MM-DD, with to"},
+	{Name: "to", Label: "Period end date", Help: "YYYY-MM-DD, with from"},
+	{Name: "period", Label: "Period expression", Help: "e.g. last-month, 2021-Q1, ytd, used without from and to", Default: defaultPeriod},
+	{Name: "basis", Label: "Accounting basis", Options: []string{"cash", "accrual"}},
+}
+
+func (s *Server) handleAPIIndex(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, &statusError{code: http.StatusMethodNotAllowed, msg: fmt.Sprintf("method %s not allowed", r.Method)})
+		return
+	}
+
+	res := []apiReport{}
+	for _, p := range pages {
+		params := append([]field{}, periodParams...)
+		if p.Compare {
+			params = append(params, field{Name: "compare", Label: "Period to compare with", Help: "previous, year-ago, a period expression or two dates, comma separated"})
+		}
+		params = append(params, p.Fields...)
+		res = append(res, apiReport{Path: apiPrefix + p.Path, Title: p.Title, Compare: p.Compare, Params: params})
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) handleAPINotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, &statusError{code: http.StatusNotFound, msg: fmt.Sprintf("unknown report %s, see %s", r.URL.Path, apiPrefix)})
+}
+
+// handleAPI responds with the report result as JSON, or the error with its status code.
+func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request, p page) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, &statusError{code: http.StatusMethodNotAllowed, msg: fmt.Sprintf("method %s not allowed", r.Method)})
+		return
+	}
+
+	q := p.query(r)
+	req, err := s.parseRequest(q)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	res, err := s.runReport(r.Context(), p, req, q)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	err := utils.WriteJSON(w, v)
+	if err != nil {
+		shop.Log.Printf("error writing response: %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := statusCode(err)
+	if code == http.StatusInternalServerError {
+		shop.Log.Printf("error running report: %s", err)
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
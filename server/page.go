@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+
+	"github.com/r0busta/go-shopify-reports/report"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/shopspring/decimal"
+)
+
+const maxChartBars = 25
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.New("").ParseFS(templateFS, "templates/*.html"))
+
+// field is a form input of a page, and a query parameter of its report. Type is an input type or select, text by default.
+type field struct {
+	Name    string   `json:"name"`
+	Label   string   `json:"label"`
+	Type    string   `json:"-"`
+	Help    string   `json:"help,omitempty"`
+	Options []string `json:"options,omitempty"`
+	Default string   `json:"default,omitempty"`
+	Value   string   `json:"-"`
+}
+
+// view is what a page template renders.
+type view struct {
+	Pages  []page
+	Page   *page
+	Fields []field
+	Result *report.Result
+	Chart  *chart
+	Error  string
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	renderPage(w, http.StatusOK, "index.html", &view{Pages: pages})
+}
+
+func (s *Server) handlePage(w http.ResponseWriter, r *http.Request, p page) {
+	q := p.query(r)
+	v := &view{
+		Pages:  pages,
+		Page:   &p,
+		Fields: s.formFields(p, q),
+	}
+
+	req, err := s.parseRequest(q)
+	if err == nil {
+		v.Result, err = s.runReport(r.Context(), p, req, q)
+	}
+	if err != nil {
+		v.Error = err.Error()
+		renderPage(w, statusCode(err), "page.html", v)
+		return
+	}
+
+	v.Chart = newChart(v.Result)
+	renderPage(w, http.StatusOK, "page.html", v)
+}
+
+// formFields returns the period, comparison and basis inputs followed by the page's own, filled in from the query.
+func (s *Server) formFields(p page, q queryValues) []field {
+	period := q.get("period")
+	if q.get("from") != "" || q.get("to") != "" {
+		period = ""
+	} else if period == "" {
+		period = defaultPeriod
+	}
+	basis := q.get("basis")
+	if basis == "" {
+		basis = string(s.opts.Basis)
+	}
+
+	res := []field{
+		{Name: "period", Label: "Period", Value: period, Help: "e.g. last-month, 2021-Q1 or ytd, unless dates are picked"},
+		{Name: "from", Label: "From", Type: "date", Value: q.get("from")},
+		{Name: "to", Label: "To", Type: "date", Value: q.get("to")},
+	}
+	if p.Compare {
+		res = append(res, field{Name: "compare", Label: "Compare with", Value: q.get("compare"), Help: "previous, year-ago or a period"})
+	}
+	res = append(res, field{Name: "basis", Label: "Basis", Type: "select", Options: []string{"cash", "accrual"}, Value: basis})
+
+	for _, f := range p.Fields {
+		f.Value = q.get(f.Name)
+		res = append(res, f)
+	}
+	return res
+}
+
+func renderPage(w http.ResponseWriter, code int, name string, v *view) {
+	var buf bytes.Buffer
+	err := templates.ExecuteTemplate(&buf, name, v)
+	if err != nil {
+		shop.Log.Printf("error rendering %s: %s", name, err)
+		http.Error(w, "error rendering page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
+}
+
+// chart is a horizontal bar chart of an amount of the report, scaled to the largest one.
+type chart struct {
+	Title string
+	Bars  []bar
+}
+
+type bar struct {
+	Label string
+	Text  string
+	// Width is the bar length in percent
+	Width float64
+}
+
+// newChart charts the first amount column of the first table by the row labels,
+// or the summary amounts if the report has no table.
+func newChart(r *report.Result) *chart {
+	labels, cells, title := []string{}, []report.Cell{}, ""
+
+	if len(r.Tables) > 0 && len(r.Tables[0].Rows) > 0 {
+		t := r.Tables[0]
+		col := -1
+		for i := range t.Columns {
+			if k := t.Rows[0][i].Kind; k == report.KindAmount || k == report.KindNegative {
+				col = i
+				break
+			}
+		}
+		if col < 0 {
+			return nil
+		}
+		title = t.Columns[col]
+		for _, row := range t.Rows {
+			label := ""
+			for _, c := range row[:col] {
+				if c.Kind != report.KindText {
+					continue
+				}
+				if label != "" {
+					label += " / "
+				}
+				label += c.Text
+			}
+			labels = append(labels, label)
+			cells = append(cells, row[col])
+		}
+	} else {
+		for _, f := range r.Summary {
+			if f.Value.Kind != report.KindAmount {
+				continue
+			}
+			labels = append(labels, f.Name)
+			cells = append(cells, f.Value)
+		}
+	}
+	if len(cells) == 0 {
+		return nil
+	}
+	if len(cells) > maxChartBars {
+		labels, cells = labels[:maxChartBars], cells[:maxChartBars]
+	}
+
+	max := decimal.Zero
+	for _, c := range cells {
+		if d, ok := c.Raw.(decimal.Decimal); ok && d.Abs().GreaterThan(max) {
+			max = d.Abs()
+		}
+	}
+
+	res := &chart{Title: title}
+	for i, c := range cells {
+		b := bar{Label: labels[i], Text: c.Text}
+		if d, ok := c.Raw.(decimal.Decimal); ok && !max.IsZero() {
+			b.Width = d.Abs().Div(max).Mul(decimal.NewFromInt(100)).InexactFloat64()
+		}
+		res.Bars = append(res.Bars, b)
+	}
+	return res
+}
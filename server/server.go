@@ -0,0 +1,346 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/r0busta/go-shopify-reports/corporatetax"
+	"github.com/r0busta/go-shopify-reports/report"
+	"github.com/r0busta/go-shopify-reports/report/render"
+	"github.com/r0busta/go-shopify-reports/sales"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/utils"
+	"github.com/r0busta/go-shopify-reports/vat"
+)
+
+const defaultPeriod = "last-month"
+
+// Server serves the reports as HTML pages and JSON, from the same order service and cache as the command line.
+type Server struct {
+	// store is the name of the store the reports are for.
+	store  string
+	orders shop.OrderService
+	opts   shop.CalcOptions
+	cached bool
+
+	mux *http.ServeMux
+	// mu runs one report at a time, as they share the order cache
+	mu sync.Mutex
+}
+
+func New(store string, orders shop.OrderService, opts shop.CalcOptions, useCached bool) *Server {
+	s := &Server{
+		store:  store,
+		orders: orders,
+		opts:   opts,
+		cached: useCached,
+		mux:    http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/", s.handleIndex)
+	s.mux.HandleFunc("/reports", s.handleAPIIndex)
+	s.mux.HandleFunc("/reports/", s.handleAPINotFound)
+	for _, p := range pages {
+		p := p
+		s.mux.HandleFunc(p.Path, func(w http.ResponseWriter, r *http.Request) {
+			s.handlePage(w, r, p)
+		})
+		s.mux.HandleFunc(apiPrefix+p.Path, func(w http.ResponseWriter, r *http.Request) {
+			s.handleAPI(w, r, p)
+		})
+	}
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	shop.Log.Printf("%s %s", r.Method, r.URL)
+	s.mux.ServeHTTP(w, r)
+}
+
+// request is a report request parsed and validated from the query.
+type request struct {
+	period  []string
+	compare []string
+	opts    shop.CalcOptions
+}
+
+// page is a dashboard page for a report, with the form fields it takes on top of the period and basis.
+type page struct {
+	Path    string
+	Title   string
+	Fields  []field
+	Compare bool
+	run     func(ctx context.Context, s *Server, req *request, q queryValues) (*report.Result, error)
+}
+
+var pages = []page{
+	{
+		Path:  "/sales",
+		Title: "Sales",
+		Fields: []field{
+			{Name: "by", Label: "Group by", Default: "vendor", Help: strings.Join(sales.DimensionNames(), ", ")},
+			{Name: "metrics", Label: "Metrics", Help: strings.Join(sales.MetricNames(), ", ")},
+			{Name: "interval", Label: "Interval", Type: "select", Options: []string{"", "day", "week", "month", "quarter"}},
+		},
+		Compare: true,
+		run: func(ctx context.Context, s *Server, req *request, q queryValues) (*report.Result, error) {
+			return s.salesReport(ctx, req, q)
+		},
+	},
+	{
+		Path:    "/sales/tag",
+		Title:   "Sales by tag",
+		Fields:  []field{{Name: "tags", Label: "Tags", Help: "comma separated, all if empty"}},
+		Compare: true,
+		run: func(ctx context.Context, s *Server, req *request, q queryValues) (*report.Result, error) {
+			r, err := sales.ByTag(ctx, s.orders, q.list("tags"), req.period, req.compare, s.cached, req.opts)
+			if err != nil {
+				return nil, err
+			}
+			return render.Sales(r), nil
+		},
+	},
+	{
+		Path:    "/sales/vendor",
+		Title:   "Sales by vendor",
+		Compare: true,
+		run: func(ctx context.Context, s *Server, req *request, q queryValues) (*report.Result, error) {
+			r, err := sales.ByVendor(ctx, s.orders, req.period, req.compare, s.cached, req.opts)
+			if err != nil {
+				return nil, err
+			}
+			return render.Sales(r), nil
+		},
+	},
+	{
+		Path:   "/sales/product",
+		Title:  "Sales by product",
+		Fields: []field{{Name: "variants", Label: "By variant SKU", Type: "checkbox"}},
+		run: func(ctx context.Context, s *Server, req *request, q queryValues) (*report.Result, error) {
+			r, err := sales.ByProduct(ctx, s.orders, req.period, s.cached, req.opts, q.bool("variants"))
+			if err != nil {
+				return nil, err
+			}
+			return render.Products(r), nil
+		},
+	},
+	{
+		Path:  "/vat",
+		Title: "VAT return",
+		Fields: []field{
+			{Name: "scheme", Label: "Scheme", Type: "select", Options: []string{"flat", "standard", "oss"}},
+			{Name: "flat-rate", Label: "Flat rate %", Help: "flat scheme"},
+			{Name: "registered", Label: "Registered on", Type: "date", Help: "flat scheme, for the first year discount"},
+			{Name: "limited-cost", Label: "Limited cost trader", Help: "start and end dates, comma separated, flat scheme"},
+			{Name: "reclaimed", Label: "VAT reclaimed"},
+			{Name: "purchases", Label: "Purchases excl. VAT", Help: "standard scheme"},
+			{Name: "eur-rate", Label: "EUR rate", Help: "oss scheme"},
+		},
+		Compare: true,
+		run: func(ctx context.Context, s *Server, req *request, q queryValues) (*report.Result, error) {
+			return s.vatReport(ctx, req, q)
+		},
+	},
+	{
+		Path:    "/corporate-tax",
+		Title:   "Corporation tax",
+		Compare: true,
+		run: func(ctx context.Context, s *Server, req *request, q queryValues) (*report.Result, error) {
+			r, err := corporatetax.Calc(ctx, s.orders, req.period, req.compare, s.cached, req.opts)
+			if err != nil {
+				return nil, err
+			}
+			return render.CorporateTax(r), nil
+		},
+	},
+}
+
+func (s *Server) salesReport(ctx context.Context, req *request, q queryValues) (*report.Result, error) {
+	by, metrics := q.list("by"), q.list("metrics")
+	interval, err := utils.ParseInterval(q.get("interval"))
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+	if len(by) == 0 && interval == "" {
+		return nil, badRequest("expected by or interval")
+	}
+	if len(req.compare) > 0 && interval != "" {
+		return nil, badRequest("compare can't be used with interval")
+	}
+	if _, err := sales.ParseDimensions(by); err != nil {
+		return nil, badRequest(err.Error())
+	}
+	if _, err := sales.ParseMetrics(metrics); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	r, err := sales.By(ctx, s.orders, by, metrics, interval, req.period, req.compare, s.cached, req.opts)
+	if err != nil {
+		return nil, err
+	}
+	return render.Sales(r), nil
+}
+
+func (s *Server) vatReport(ctx context.Context, req *request, q queryValues) (*report.Result, error) {
+	params := vat.Params{
+		Reclaimed:    q.get("reclaimed"),
+		Purchases:    q.get("purchases"),
+		FlatRate:     q.get("flat-rate"),
+		RegisteredOn: q.get("registered"),
+		LimitedCost:  q.list("limited-cost"),
+		EURRate:      q.get("eur-rate"),
+	}
+
+	scheme := q.get("scheme")
+	if scheme == "" {
+		scheme = "flat"
+	}
+	if scheme == "oss" {
+		if len(req.compare) > 0 {
+			return nil, badRequest("comparing periods isn't supported for the oss scheme")
+		}
+		r, err := vat.NewOSSReturn(params)
+		if err != nil {
+			return nil, badRequest(err.Error())
+		}
+		ret, err := r.Report(ctx, s.orders, req.period, s.cached, req.opts)
+		if errors.Is(err, vat.ErrNoEURRate) {
+			return nil, badRequest(err.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return render.OSSReturn(ret), nil
+	}
+
+	r, err := vat.NewReturn(scheme, params, req.opts.Location)
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+	if len(req.compare) > 0 {
+		c, err := vat.CompareReport(ctx, r, s.orders, req.period, req.compare, s.cached, req.opts)
+		if err != nil {
+			return nil, err
+		}
+		return render.VATComparison(c), nil
+	}
+	ret, err := r.Report(ctx, s.orders, req.period, s.cached, req.opts)
+	if err != nil {
+		return nil, err
+	}
+	return render.VATReturn(ret), nil
+}
+
+// runReport runs the page's report, one at a time, for the store of the server.
+func (s *Server) runReport(ctx context.Context, p page, req *request, q queryValues) (*report.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := p.run(ctx, s, req, q)
+	if err != nil {
+		return nil, err
+	}
+	res.Store = s.store
+	return res, nil
+}
+
+// query returns the query of the request with the defaults of the page fields.
+func (p *page) query(r *http.Request) queryValues {
+	q := queryValues(r.URL.Query())
+	for _, f := range p.Fields {
+		if f.Default != "" && q.get(f.Name) == "" {
+			q[f.Name] = []string{f.Default}
+		}
+	}
+	return q
+}
+
+// parseRequest validates the period, the period to compare with and the basis of the query.
+// The period is the from and to dates if they're given, or else the period expression, last month by default.
+func (s *Server) parseRequest(q queryValues) (*request, error) {
+	req := &request{opts: s.opts}
+
+	switch {
+	case q.get("from") != "" || q.get("to") != "":
+		req.period = []string{q.get("from"), q.get("to")}
+	case q.get("period") != "":
+		req.period = []string{q.get("period")}
+	default:
+		req.period = []string{defaultPeriod}
+	}
+	from, to, err := utils.ParsePeriodIn(req.period, req.opts.Location)
+	if err != nil {
+		return nil, badRequest(fmt.Sprintf("invalid period: %s", err))
+	}
+
+	req.compare = q.list("compare")
+	if len(req.compare) > 0 {
+		_, _, err = utils.ParseComparePeriod(req.compare, *from, *to)
+		if err != nil {
+			return nil, badRequest(fmt.Sprintf("invalid period to compare with: %s", err))
+		}
+	}
+
+	if basis := q.get("basis"); basis != "" {
+		req.opts.Basis, err = shop.ParseBasis(basis)
+		if err != nil {
+			return nil, badRequest(err.Error())
+		}
+	}
+
+	return req, nil
+}
+
+// statusError is an error with the HTTP status code to respond with.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+func badRequest(msg string) error {
+	return &statusError{code: http.StatusBadRequest, msg: msg}
+}
+
+func statusCode(err error) int {
+	if e, ok := err.(*statusError); ok {
+		return e.code
+	}
+	return http.StatusInternalServerError
+}
+
+type queryValues map[string][]string
+
+func (q queryValues) get(name string) string {
+	if v := q[name]; len(v) > 0 {
+		return strings.TrimSpace(v[0])
+	}
+	return ""
+}
+
+// list returns the comma separated values of the parameter.
+func (q queryValues) list(name string) []string {
+	res := []string{}
+	for _, v := range strings.Split(q.get(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func (q queryValues) bool(name string) bool {
+	switch q.get(name) {
+	case "1", "true", "on", "yes":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/shop/shoptest"
+	"gopkg.in/guregu/null.v4"
+)
+
+func newOrders() *shoptest.OrderService {
+	return newOrdersIn(model.CurrencyCodeGbp, model.CountryCodeGb)
+}
+
+// newOrdersIn returns an order in the currency, shipped to the country.
+func newOrdersIn(currency model.CurrencyCode, country model.CountryCode) *shoptest.OrderService {
+	vendor := "Acme"
+	at := time.Date(2020, 4, 10, 9, 0, 0, 0, time.UTC).Format(shop.ISO8601Layout)
+	return &shoptest.OrderService{Orders: []*model.Order{
+		{
+			Name:            "#1001",
+			CreatedAt:       at,
+			UpdatedAt:       at,
+			ShippingAddress: &model.MailingAddress{CountryCodeV2: &country},
+			Transactions: []model.OrderTransaction{
+				{
+					ProcessedAt: model.NewString(at),
+					Kind:        model.OrderTransactionKindSale,
+					Status:      model.OrderTransactionStatusSuccess,
+					AmountSet: &model.MoneyBag{
+						ShopMoney: &model.MoneyV2{
+							Amount:       null.StringFrom("30.00"),
+							CurrencyCode: currency,
+						},
+					},
+				},
+			},
+			LineItems: &model.LineItemConnection{
+				Edges: []model.LineItemEdge{
+					{
+						Node: &model.LineItem{
+							Vendor:          &vendor,
+							Quantity:        1,
+							CurrentQuantity: 1,
+							DiscountedTotalSet: &model.MoneyBag{
+								ShopMoney: &model.MoneyV2{
+									Amount:       null.StringFrom("30.00"),
+									CurrencyCode: currency,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func TestServer(t *testing.T) {
+	srv := httptest.NewServer(New("acme", newOrders(), shop.CalcOptions{Location: time.UTC}, false))
+	defer srv.Close()
+
+	tests := []struct {
+		path     string
+		wantCode int
+		want     []string
+	}{
+		{"/", http.StatusOK, []string{`href="/sales/vendor"`, "Corporation tax"}},
+		{"/sales/vendor?from=2020-04-01&to=2020-04-30", http.StatusOK, []string{"Store: acme", "Acme", "30.00", "2020-04-01 - 2020-04-30"}},
+		{"/sales/product?period=2020-04&variants=1", http.StatusOK, []string{"SKU", `name="variants" value="1" checked`}},
+		{"/corporate-tax?period=2020-04&compare=previous", http.StatusOK, []string{"Total turnover (excl. VAT)", "compared with 2020-03-01 - 2020-03-31"}},
+		{"/vat?scheme=flat&flat-rate=7.5&period=2020-04", http.StatusOK, []string{"Total turnover, including VAT and EC sales (box 6)", "<option selected>flat</option>"}},
+		{"/vat?scheme=flat&period=2020-04", http.StatusBadRequest, []string{"flat rate percentage is required"}},
+		{"/sales/tag?period=last-fortnight", http.StatusBadRequest, []string{"invalid period"}},
+		{"/sales/tag?period=2020-04&basis=deferred", http.StatusBadRequest, []string{"unknown accounting basis"}},
+		{"/nope", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatalf("GET %s, err=%v", tt.path, err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("GET %s status = %d, want %d", tt.path, resp.StatusCode, tt.wantCode)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(string(body), w) {
+					t.Errorf("GET %s body doesn't contain %q:\n%s", tt.path, w, body)
+				}
+			}
+		})
+	}
+}
+
+func TestOSSReturn(t *testing.T) {
+	tests := []struct {
+		name     string
+		orders   *shoptest.OrderService
+		wantCode int
+		want     []string
+	}{
+		{"EUR shop", newOrdersIn(model.CurrencyCodeEur, model.CountryCodeDe), http.StatusOK, []string{"Total OSS VAT payable (EUR)", "5.00"}},
+		{"GBP shop", newOrdersIn(model.CurrencyCodeGbp, model.CountryCodeDe), http.StatusBadRequest, []string{"EUR exchange rate not set"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate := 0.2
+			tt.orders.Orders[0].TaxLines = []model.TaxLine{{Rate: &rate}}
+			srv := httptest.NewServer(New("acme", tt.orders, shop.CalcOptions{Location: time.UTC}, false))
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL + "/vat?scheme=oss&period=2020-04")
+			if err != nil {
+				t.Fatalf("GET, err=%v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("GET status = %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(string(body), w) {
+					t.Errorf("GET body doesn't contain %q:\n%s", w, body)
+				}
+			}
+		})
+	}
+}
+
+func TestAPI(t *testing.T) {
+	srv := httptest.NewServer(New("acme", newOrders(), shop.CalcOptions{Location: time.UTC}, false))
+	defer srv.Close()
+
+	tests := []struct {
+		method   string
+		path     string
+		wantCode int
+		want     []string
+	}{
+		{http.MethodGet, "/reports", http.StatusOK, []string{`"path":"/reports/vat"`, `"name":"scheme"`}},
+		{http.MethodGet, "/reports/sales/vendor?from=2020-04-01&to=2020-04-30", http.StatusOK, []string{`"title":"Sales by vendor","store":"acme"`, `"Vendor":"Acme"`, `"Revenue":"30"`}},
+		{http.MethodGet, "/reports/sales?by=vendor&interval=month&period=2020-04", http.StatusOK, []string{`"Period":"2020-04"`, `"Items Ordered":1`}},
+		{http.MethodGet, "/reports/sales?period=2020-04", http.StatusOK, []string{`"Vendor":"Acme"`}},
+		{http.MethodGet, "/reports/vat?scheme=flat&flat-rate=7.5&from=2020-04-01&to=2020-04-30", http.StatusOK, []string{`"name":"VAT due on sales and other outputs (box 1)","value":"2.25"`}},
+		{http.MethodGet, "/reports/corporate-tax?period=2020-04", http.StatusOK, []string{`"name":"Total turnover (excl. VAT)","value":"30"`}},
+		{http.MethodGet, "/reports/vat?scheme=flat", http.StatusBadRequest, []string{`{"error":"flat rate percentage is required for the flat scheme"}`}},
+		{http.MethodGet, "/reports/vat?scheme=mixed&from=2020-04-01&to=2020-04-30", http.StatusBadRequest, []string{"unimplemented scheme"}},
+		{http.MethodGet, "/reports/sales?by=colour&period=2020-04", http.StatusBadRequest, []string{"colour"}},
+		{http.MethodGet, "/reports/sales?by=vendor&interval=day&compare=previous&period=2020-04", http.StatusBadRequest, []string{"compare can't be used with interval"}},
+		{http.MethodGet, "/reports/sales/tag?from=2020-04-31&to=2020-05-01", http.StatusBadRequest, []string{"invalid period"}},
+		{http.MethodGet, "/reports/sales/tag?from=2020-05-01&to=2020-04-01", http.StatusBadRequest, []string{"period ends before it starts"}},
+		{http.MethodGet, "/reports/corporate-tax?period=2020-04&compare=2020-01-01", http.StatusBadRequest, []string{"invalid period to compare with"}},
+		{http.MethodPost, "/reports/corporate-tax", http.StatusMethodNotAllowed, []string{"method POST not allowed"}},
+		{http.MethodGet, "/reports/profit", http.StatusNotFound, []string{"unknown report /reports/profit"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("NewRequest(), err=%v", err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s, err=%v", tt.method, tt.path, err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("%s %s status = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.wantCode)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("%s %s content type = %s, want application/json", tt.method, tt.path, ct)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(string(body), w) {
+					t.Errorf("%s %s body doesn't contain %q:\n%s", tt.method, tt.path, w, body)
+				}
+			}
+		})
+	}
+}
+
+// failingOrders fails to list orders, e.g. when Shopify is unreachable.
+type failingOrders struct{}
+
+func (failingOrders) ListCreatedBetween(ctx context.Context, from, to time.Time, useCached bool) ([]*model.Order, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (failingOrders) ListUpdatedSince(ctx context.Context, since *time.Time) ([]*model.Order, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestReportError(t *testing.T) {
+	srv := httptest.NewServer(New("acme", failingOrders{}, shop.CalcOptions{Location: time.UTC}, false))
+	defer srv.Close()
+
+	for _, path := range []string{"/reports/corporate-tax?period=2020-04", "/corporate-tax?period=2020-04"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s, err=%v", path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Errorf("GET %s status = %d, want %d", path, resp.StatusCode, http.StatusInternalServerError)
+		}
+		if want := "error getting orders: connection refused"; !strings.Contains(string(body), want) {
+			t.Errorf("GET %s body doesn't contain %q:\n%s", path, want, body)
+		}
+	}
+}
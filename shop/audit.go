@@ -0,0 +1,136 @@
+package shop
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/shopspring/decimal"
+)
+
+// Audit rules, why a transaction is included in or excluded from the turnover of a period.
+const (
+	RuleInPeriod        = "in-period"
+	RuleOrderedInPeriod = "ordered-in-period"
+	RuleTest            = "test"
+	RuleKind            = "kind"
+	RuleStatus          = "status"
+	RuleOutOfPeriod     = "out-of-period"
+)
+
+// AuditEntry is an order transaction considered for the turnover of a period.
+// Amount is the transaction shop money, Turnover and Tax what it adds to the period's turnover and VAT
+// in the report currency, refunds taking off.
+type AuditEntry struct {
+	Order       string
+	Kind        model.OrderTransactionKind
+	Status      model.OrderTransactionStatus
+	ProcessedAt *time.Time
+	Amount      Money
+	Included    bool
+	Rule        string
+	Turnover    decimal.Decimal
+	Tax         decimal.Decimal
+}
+
+// AuditTransactions lists the transactions of the orders with the rule CalcOrderTurnover includes or excludes them by.
+// The order VAT is apportioned to its included transactions, so the entries add up to CalcTotalTurnover
+// and CalcTotalSaleTax.
+func AuditTransactions(orders []*model.Order, from, to time.Time, opts CalcOptions) ([]AuditEntry, error) {
+	res := []AuditEntry{}
+	for _, o := range orders {
+		entries, err := auditOrder(o, from, to, opts)
+		if err != nil {
+			return nil, fmt.Errorf("auditing order %s: %w", o.Name, err)
+		}
+		res = append(res, entries...)
+	}
+	return res, nil
+}
+
+func auditOrder(o *model.Order, from, to time.Time, opts CalcOptions) ([]AuditEntry, error) {
+	created, err := IsOrderCreatedInPeriod(o, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []AuditEntry{}
+	included := []int{}
+	weights := []decimal.Decimal{}
+	for _, t := range o.Transactions {
+		e := AuditEntry{
+			Order:  o.Name,
+			Kind:   t.Kind,
+			Status: t.Status,
+		}
+		if t.AmountSet != nil {
+			m, err := ParseMoney(opts.money(t.AmountSet))
+			if err != nil {
+				return nil, fmt.Errorf("error parsing transaction amount: %w", err)
+			}
+			e.Amount = *m
+		}
+		if t.ProcessedAt != nil {
+			processedAt, err := time.Parse(ISO8601Layout, *t.ProcessedAt)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing processed at time: %w", err)
+			}
+			e.ProcessedAt = &processedAt
+		}
+
+		e.Rule = auditRule(t, e.ProcessedAt, created, from, to, opts)
+		e.Included = e.Rule == RuleInPeriod || e.Rule == RuleOrderedInPeriod
+		if e.Included {
+			e.Turnover, err = opts.Convert(e.Amount, *e.ProcessedAt)
+			if err != nil {
+				return nil, fmt.Errorf("error converting transaction amount: %w", err)
+			}
+			if t.Kind == model.OrderTransactionKindRefund {
+				e.Turnover = e.Turnover.Neg()
+			}
+			included = append(included, len(res))
+			weights = append(weights, e.Turnover)
+		}
+		res = append(res, e)
+	}
+
+	tax, err := CalcOrderSaleTax(o, from, to, opts)
+	if err != nil {
+		return nil, err
+	}
+	rest := *tax
+	for i, share := range apportion(*tax, weights) {
+		if i < len(included)-1 {
+			share = share.Round(2)
+			rest = rest.Sub(share)
+		} else {
+			share = rest
+		}
+		res[included[i]].Tax = share
+	}
+
+	return res, nil
+}
+
+// auditRule returns the rule the transaction is included or excluded by, checked in the order SumTransactions does.
+func auditRule(t model.OrderTransaction, processedAt *time.Time, created bool, from, to time.Time, opts CalcOptions) string {
+	switch {
+	case t.Test:
+		return RuleTest
+	case t.Kind != model.OrderTransactionKindSale && t.Kind != model.OrderTransactionKindRefund:
+		return RuleKind
+	case t.Status != model.OrderTransactionStatusSuccess:
+		return RuleStatus
+	case processedAt == nil:
+		return RuleOutOfPeriod
+	case t.Kind == model.OrderTransactionKindSale && opts.Basis == BasisAccrual:
+		if created {
+			return RuleOrderedInPeriod
+		}
+		return RuleOutOfPeriod
+	case isInPeriod(*processedAt, from, to):
+		return RuleInPeriod
+	default:
+		return RuleOutOfPeriod
+	}
+}
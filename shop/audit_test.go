@@ -0,0 +1,104 @@
+package shop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop/shoptest"
+	"github.com/r0busta/go-shopify-reports/utils"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestAuditTransactions(t *testing.T) {
+	from, to, err := utils.ParsePeriod([]string{"2020-04-01", "2020-04-30"})
+	if err != nil {
+		t.Fatalf("error parsing period: %s", err)
+	}
+
+	newTransaction := func(kind model.OrderTransactionKind, status model.OrderTransactionStatus, test bool, processedAt time.Time, amount string) model.OrderTransaction {
+		return model.OrderTransaction{
+			ProcessedAt: model.NewString(processedAt.Format(ISO8601Layout)),
+			Kind:        kind,
+			Status:      status,
+			Test:        test,
+			AmountSet: &model.MoneyBag{
+				ShopMoney: &model.MoneyV2{
+					Amount: null.StringFrom(amount),
+				},
+			},
+		}
+	}
+	inPeriod := time.Date(2020, 4, 10, 10, 0, 0, 0, time.UTC)
+	before := time.Date(2020, 3, 31, 23, 0, 0, 0, time.UTC)
+
+	orders := []*model.Order{
+		{
+			Name:      "#1001",
+			CreatedAt: before.Format(ISO8601Layout),
+			ShippingAddress: &model.MailingAddress{
+				CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeGb),
+			},
+			TaxLines: []model.TaxLine{{Rate: shoptest.NewFloat64(0.2)}},
+			Transactions: []model.OrderTransaction{
+				newTransaction(model.OrderTransactionKindSale, model.OrderTransactionStatusSuccess, false, before, "100.00"),
+				newTransaction(model.OrderTransactionKindSale, model.OrderTransactionStatusSuccess, false, inPeriod, "10.01"),
+				newTransaction(model.OrderTransactionKindSale, model.OrderTransactionStatusSuccess, true, inPeriod, "50.00"),
+				newTransaction(model.OrderTransactionKindAuthorization, model.OrderTransactionStatusSuccess, false, inPeriod, "50.00"),
+				newTransaction(model.OrderTransactionKindSale, model.OrderTransactionStatusFailure, false, inPeriod, "50.00"),
+				newTransaction(model.OrderTransactionKindRefund, model.OrderTransactionStatusSuccess, false, inPeriod, "20.00"),
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		basis     Basis
+		rules     []string
+		turnovers []string
+	}{
+		{
+			name:      "Cash basis",
+			basis:     BasisCash,
+			rules:     []string{RuleOutOfPeriod, RuleInPeriod, RuleTest, RuleKind, RuleStatus, RuleInPeriod},
+			turnovers: []string{"0", "10.01", "0", "0", "0", "-20"},
+		},
+		{
+			name:      "Accrual basis, ordered before the period",
+			basis:     BasisAccrual,
+			rules:     []string{RuleOutOfPeriod, RuleOutOfPeriod, RuleTest, RuleKind, RuleStatus, RuleInPeriod},
+			turnovers: []string{"0", "0", "0", "0", "0", "-20"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := CalcOptions{Basis: tt.basis}
+			got, err := AuditTransactions(orders, *from, *to, opts)
+			if err != nil {
+				t.Fatalf("AuditTransactions(), err=%v", err)
+			}
+			if len(got) != len(tt.rules) {
+				t.Fatalf("AuditTransactions() got %d entries, want %d", len(got), len(tt.rules))
+			}
+
+			turnover, tax := decimal.Zero, decimal.Zero
+			for i, e := range got {
+				if e.Rule != tt.rules[i] {
+					t.Errorf("AuditTransactions() entry %d rule = %s, want %s", i, e.Rule, tt.rules[i])
+				}
+				if !e.Turnover.Equal(decimal.RequireFromString(tt.turnovers[i])) {
+					t.Errorf("AuditTransactions() entry %d turnover = %s, want %s", i, e.Turnover, tt.turnovers[i])
+				}
+				turnover = turnover.Add(e.Turnover)
+				tax = tax.Add(e.Tax)
+			}
+
+			wantTurnover, _ := CalcTotalTurnover(orders, *from, *to, opts)
+			wantTax, _ := CalcTotalSaleTax(orders, *from, *to, opts)
+			if !turnover.Equal(*wantTurnover) || !tax.Equal(*wantTax) {
+				t.Errorf("AuditTransactions() entries add up to %s/%s, want %s/%s", turnover, tax, wantTurnover, wantTax)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package shop
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	diskstore "github.com/r0busta/go-object-store/disk"
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+)
+
+const (
+	cacheFileLayout = "20060102T150405"
+)
+
+type CacheMeta struct {
+	Store     string    `json:"store"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	QueryHash string    `json:"queryHash"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// Covers reports whether the cached entry holds a complete snapshot for the requested range.
+// A snapshot fetched before the end of the range is incomplete, so it doesn't cover it.
+func (m *CacheMeta) Covers(store string, from, to time.Time, queryHash string) bool {
+	if m.Store != store || m.QueryHash != queryHash {
+		return false
+	}
+	if m.From.After(from) || m.To.Before(to) {
+		return false
+	}
+	return m.FetchedAt.After(to)
+}
+
+type OrderCache struct {
+	dir   string
+	store string
+}
+
+func NewOrderCache(dir, store string) *OrderCache {
+	return &OrderCache{
+		dir:   dir,
+		store: store,
+	}
+}
+
+// Lookup returns the orders of the most recently fetched entry covering the range, or nil if there's none.
+func (c *OrderCache) Lookup(from, to time.Time, query string) ([]*model.Order, *CacheMeta, error) {
+	queryHash := hashQuery(query)
+
+	metaFiles, err := filepath.Glob(filepath.Join(c.dir, fmt.Sprintf("%s_*.meta.json", c.store)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing cache entries: %w", err)
+	}
+
+	var found *CacheMeta
+	var foundPath string
+	for _, f := range metaFiles {
+		meta := &CacheMeta{}
+		err := diskstore.New(f).Read(meta)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading cache metadata %s: %w", f, err)
+		}
+		if !meta.Covers(c.store, from, to, queryHash) {
+			continue
+		}
+		if found == nil || meta.FetchedAt.After(found.FetchedAt) {
+			found = meta
+			foundPath = f
+		}
+	}
+
+	if found == nil {
+		return nil, nil, nil
+	}
+
+	store := diskstore.New(foundPath[:len(foundPath)-len(".meta.json")] + ".json")
+	if !store.FileExists() {
+		return nil, nil, nil
+	}
+
+	orders := []*model.Order{}
+	err = store.Read(&orders)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading cached orders: %w", err)
+	}
+
+	return FilterOrdersInRange(orders, from, to), found, nil
+}
+
+func (c *OrderCache) Save(from, to time.Time, query string, orders []*model.Order, fetchedAt time.Time) error {
+	err := os.MkdirAll(c.dir, 0755)
+	if err != nil {
+		return fmt.Errorf("creating cache dir: %w", err)
+	}
+
+	meta := &CacheMeta{
+		Store:     c.store,
+		From:      from,
+		To:        to,
+		QueryHash: hashQuery(query),
+		FetchedAt: fetchedAt,
+	}
+
+	name := filepath.Join(c.dir, fmt.Sprintf("%s_%s_%s_%s", c.store, from.UTC().Format(cacheFileLayout), to.UTC().Format(cacheFileLayout), meta.QueryHash))
+
+	err = diskstore.New(name + ".json").Write(orders)
+	if err != nil {
+		return fmt.Errorf("writing cached orders: %w", err)
+	}
+
+	err = diskstore.New(name + ".meta.json").Write(meta)
+	if err != nil {
+		return fmt.Errorf("writing cache metadata: %w", err)
+	}
+
+	return nil
+}
+
+// shopSettings are the shop settings saved with the cached orders, so reports from them don't query the shop.
+type shopSettings struct {
+	Timezone string `json:"timezone"`
+}
+
+// Timezone returns the shop IANA timezone saved with the cached orders, or an empty string if there's none.
+func (c *OrderCache) Timezone() (string, error) {
+	store := diskstore.New(filepath.Join(c.dir, fmt.Sprintf("%s_shop.json", c.store)))
+	if !store.FileExists() {
+		return "", nil
+	}
+	settings := &shopSettings{}
+	err := store.Read(settings)
+	if err != nil {
+		return "", fmt.Errorf("reading cached shop settings: %w", err)
+	}
+	return settings.Timezone, nil
+}
+
+func (c *OrderCache) SaveTimezone(tz string) error {
+	err := os.MkdirAll(c.dir, 0755)
+	if err != nil {
+		return fmt.Errorf("creating cache dir: %w", err)
+	}
+	err = diskstore.New(filepath.Join(c.dir, fmt.Sprintf("%s_shop.json", c.store))).Write(&shopSettings{Timezone: tz})
+	if err != nil {
+		return fmt.Errorf("writing cached shop settings: %w", err)
+	}
+	return nil
+}
+
+func hashQuery(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])[:12]
+}
@@ -0,0 +1,70 @@
+package shop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+)
+
+func TestOrderCacheLookup(t *testing.T) {
+	c := NewOrderCache(t.TempDir(), "store")
+
+	q1From := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	q1To := time.Date(2020, 3, 31, 23, 59, 59, 0, time.UTC)
+	q2From := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	q2To := time.Date(2020, 6, 30, 23, 59, 59, 0, time.UTC)
+
+	orders := []*model.Order{
+		{ID: "gid://shopify/Order/1", CreatedAt: "2020-01-15T10:00:00Z"},
+		{ID: "gid://shopify/Order/2", CreatedAt: "2020-03-15T10:00:00Z"},
+	}
+	err := c.Save(q1From, q1To, ordersQuery, orders, q1To.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Save(), err=%v", err)
+	}
+
+	got, meta, err := c.Lookup(q1From, q1To, ordersQuery)
+	if err != nil {
+		t.Fatalf("Lookup(), err=%v", err)
+	}
+	if meta == nil || len(got) != 2 {
+		t.Errorf("Lookup() for the cached range = %d orders, want 2", len(got))
+	}
+
+	got, _, err = c.Lookup(q1From, time.Date(2020, 1, 31, 23, 59, 59, 0, time.UTC), ordersQuery)
+	if err != nil {
+		t.Fatalf("Lookup(), err=%v", err)
+	}
+	if len(got) != 1 || got[0].ID != "gid://shopify/Order/1" {
+		t.Errorf("Lookup() for a sub-range = %v, want only the first order", got)
+	}
+
+	got, _, err = c.Lookup(q2From, q2To, ordersQuery)
+	if err != nil {
+		t.Fatalf("Lookup(), err=%v", err)
+	}
+	if got != nil {
+		t.Errorf("Lookup() for an uncovered range = %v, want nil", got)
+	}
+
+	got, _, err = c.Lookup(q1From, q1To, "{ orders { edges { node { id } } } }")
+	if err != nil {
+		t.Fatalf("Lookup(), err=%v", err)
+	}
+	if got != nil {
+		t.Errorf("Lookup() for another query shape = %v, want nil", got)
+	}
+
+	err = c.Save(q2From, q2To, ordersQuery, orders, q2To.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Save(), err=%v", err)
+	}
+	got, _, err = c.Lookup(q2From, q2To, ordersQuery)
+	if err != nil {
+		t.Fatalf("Lookup(), err=%v", err)
+	}
+	if got != nil {
+		t.Errorf("Lookup() for a range fetched before it ended = %v, want nil", got)
+	}
+}
@@ -1,29 +1,158 @@
 package shop
 
 import (
-	diskstore "github.com/r0busta/go-object-store/disk"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
 	shopifygraphql "github.com/r0busta/go-shopify-graphql/v8"
+	"github.com/r0busta/graphql"
 )
 
 const (
 	ISO8601Layout = "2006-01-02T15:04:05Z"
+
+	cacheDir = "_cache"
 )
 
 type Client struct {
 	shopifyClient *shopifygraphql.Client
+	cache         *OrderCache
+	db            *OrderDB
+	// stores are the clients of the stores a consolidated client lists orders from
+	stores []*Client
+
+	name     string
+	timezone string
+	currency model.CurrencyCode
 
 	Order OrderService
 }
 
+// NewClient returns a client for the store's Admin API, or for the GraphQL endpoint at STORE_API_URL if it's set,
+// e.g. a fake store (see shoptest).
 func NewClient() *Client {
+	var shopifyClient *shopifygraphql.Client
+	if url := os.Getenv("STORE_API_URL"); url != "" {
+		shopifyClient = shopifygraphql.NewClient(shopifygraphql.WithGraphQLClient(graphql.NewClient(url, http.DefaultClient)))
+	} else {
+		shopifyClient = shopifygraphql.NewDefaultClient()
+	}
+
+	return newOnlineClient(shopifyClient, os.Getenv("STORE_NAME"))
+}
+
+// NewStoreClient returns a client for the Admin API of the store profile.
+func NewStoreClient(s Store) *Client {
+	var shopifyClient *shopifygraphql.Client
+	if s.APIURL != "" {
+		shopifyClient = shopifygraphql.NewClient(shopifygraphql.WithGraphQLClient(graphql.NewClient(s.APIURL, http.DefaultClient)))
+	} else {
+		shopifyClient = shopifygraphql.NewClientWithToken(s.Token, s.shopName())
+	}
+
+	c := newOnlineClient(shopifyClient, s.Name)
+	c.timezone = s.Timezone
+	c.currency = model.CurrencyCode(strings.ToUpper(s.Currency))
+	return c
+}
+
+func newOnlineClient(shopifyClient *shopifygraphql.Client, name string) *Client {
 	c := &Client{
-		shopifyClient: shopifygraphql.NewDefaultClient(),
+		shopifyClient: shopifyClient,
+		cache:         NewOrderCache(cacheDir, name),
+		name:          name,
 	}
 
 	c.Order = &OrderServiceOp{
 		client: c,
-		cache:  diskstore.New("_orders_cache.json"),
+		cache:  c.cache,
 	}
 
 	return c
 }
+
+// NewOfflineClient returns a client serving orders from the local database only.
+func NewOfflineClient(dbPath string) (*Client, error) {
+	db, err := OpenOrderDB(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		db:   db,
+		name: os.Getenv("STORE_NAME"),
+	}
+
+	c.Order = &OrderDBServiceOp{
+		db: db,
+	}
+
+	return c, nil
+}
+
+// NewOfflineStoreClient returns a client serving the orders of the store profile from its local database.
+func NewOfflineStoreClient(s Store, dbPath string) (*Client, error) {
+	c, err := NewOfflineClient(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	c.name = s.Name
+	c.timezone = s.Timezone
+	c.currency = model.CurrencyCode(strings.ToUpper(s.Currency))
+	return c, nil
+}
+
+// NewMultiStoreClient returns a client listing the orders of all the store clients, see MultiStoreService.
+func NewMultiStoreClient(clients ...*Client) *Client {
+	service := NewMultiStoreService()
+	for _, sc := range clients {
+		service.Add(sc.name, sc.Order)
+	}
+
+	return &Client{
+		stores: clients,
+		Order:  service,
+	}
+}
+
+// Name returns the store name, or the names of the stores of a consolidated client.
+func (c *Client) Name() string {
+	if len(c.stores) == 0 {
+		return c.name
+	}
+	names := []string{}
+	for _, sc := range c.stores {
+		names = append(names, sc.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Currency returns the default report currency of the store profile, or of all the stores if they have the same.
+// It's empty when the store doesn't set one.
+func (c *Client) Currency() model.CurrencyCode {
+	if len(c.stores) == 0 {
+		return c.currency
+	}
+	res := c.stores[0].Currency()
+	for _, sc := range c.stores[1:] {
+		if sc.Currency() != res {
+			return ""
+		}
+	}
+	return res
+}
+
+func (c *Client) Close() error {
+	var err error
+	for _, sc := range c.stores {
+		if e := sc.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	if c.db != nil {
+		return c.db.Close()
+	}
+	return err
+}
@@ -0,0 +1,26 @@
+package shop
+
+import (
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/thoas/go-funk"
+)
+
+var EUCountryCodes = []model.CountryCode{
+	model.CountryCodeAt, model.CountryCodeBe, model.CountryCodeBg, model.CountryCodeHr, model.CountryCodeCy,
+	model.CountryCodeCz, model.CountryCodeDk, model.CountryCodeEe, model.CountryCodeFi, model.CountryCodeFr,
+	model.CountryCodeDe, model.CountryCodeGr, model.CountryCodeHu, model.CountryCodeIe, model.CountryCodeIt,
+	model.CountryCodeLv, model.CountryCodeLt, model.CountryCodeLu, model.CountryCodeMt, model.CountryCodeNl,
+	model.CountryCodePl, model.CountryCodePt, model.CountryCodeRo, model.CountryCodeSk, model.CountryCodeSi,
+	model.CountryCodeEs, model.CountryCodeSe,
+}
+
+func GetOrderCountryCode(o *model.Order) *model.CountryCode {
+	if o.ShippingAddress == nil {
+		return nil
+	}
+	return o.ShippingAddress.CountryCodeV2
+}
+
+func IsEUCountry(code *model.CountryCode) bool {
+	return code != nil && funk.Contains(EUCountryCodes, *code)
+}
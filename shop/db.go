@@ -0,0 +1,260 @@
+package shop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	ordersBucket = []byte("orders")
+	metaBucket   = []byte("meta")
+
+	watermarkKey = []byte("watermark")
+	timezoneKey  = []byte("timezone")
+	queryHashKey = []byte("queryHash")
+)
+
+// OrderDB is a local order store kept up to date by SyncOrders.
+type OrderDB struct {
+	db *bolt.DB
+}
+
+func OpenOrderDB(path string) (*OrderDB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening order database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{ordersBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating buckets: %w", err)
+	}
+
+	return &OrderDB{db: db}, nil
+}
+
+func (d *OrderDB) Close() error {
+	return d.db.Close()
+}
+
+func (d *OrderDB) Upsert(orders []*model.Order) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(ordersBucket)
+		for _, o := range orders {
+			v, err := json.Marshal(o)
+			if err != nil {
+				return fmt.Errorf("encoding order %s: %w", o.ID, err)
+			}
+			err = b.Put([]byte(o.ID), v)
+			if err != nil {
+				return fmt.Errorf("storing order %s: %w", o.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (d *OrderDB) All() ([]*model.Order, error) {
+	orders := []*model.Order{}
+	err := d.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(ordersBucket).ForEach(func(k, v []byte) error {
+			o := &model.Order{}
+			err := json.Unmarshal(v, o)
+			if err != nil {
+				return fmt.Errorf("decoding order %s: %w", k, err)
+			}
+			orders = append(orders, o)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// Watermark returns the latest order update time seen by the last sync, or nil if it never ran.
+func (d *OrderDB) Watermark() (*time.Time, error) {
+	var res *time.Time
+	err := d.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(metaBucket).Get(watermarkKey)
+		if v == nil {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, string(v))
+		if err != nil {
+			return fmt.Errorf("parsing watermark: %w", err)
+		}
+		res = &t
+		return nil
+	})
+	return res, err
+}
+
+func (d *OrderDB) SetWatermark(t time.Time) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(watermarkKey, []byte(t.UTC().Format(time.RFC3339)))
+	})
+}
+
+// QueryHash returns the hash of the order query shape of the last sync, or an empty string if it never ran.
+func (d *OrderDB) QueryHash() (string, error) {
+	var res string
+	err := d.db.View(func(tx *bolt.Tx) error {
+		res = string(tx.Bucket(metaBucket).Get(queryHashKey))
+		return nil
+	})
+	return res, err
+}
+
+func (d *OrderDB) SetQueryHash(hash string) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(queryHashKey, []byte(hash))
+	})
+}
+
+// SyncOrders pulls the orders updated since the database watermark and upserts them. All the orders are pulled again
+// when the order query shape changed since the last sync, as the stored ones miss the fields it added.
+func SyncOrders(ctx context.Context, s OrderService, db *OrderDB) (int, error) {
+	since, err := db.Watermark()
+	if err != nil {
+		return 0, fmt.Errorf("error getting watermark: %w", err)
+	}
+	queryHash, err := db.QueryHash()
+	if err != nil {
+		return 0, fmt.Errorf("error getting query hash: %w", err)
+	}
+	if since != nil && queryHash != hashQuery(ordersQueryShape) {
+		Log.Printf("The order query changed since the last sync, pulling all the orders again")
+		since = nil
+	}
+
+	orders, err := s.ListUpdatedSince(ctx, since)
+	if err != nil {
+		return 0, fmt.Errorf("error listing updated orders: %w", err)
+	}
+
+	err = db.Upsert(orders)
+	if err != nil {
+		return 0, fmt.Errorf("error storing orders: %w", err)
+	}
+
+	watermark := since
+	for _, o := range orders {
+		updatedAt, err := time.Parse(ISO8601Layout, o.UpdatedAt)
+		if err != nil {
+			return 0, fmt.Errorf("error parsing updated at time: %w", err)
+		}
+		if watermark == nil || updatedAt.After(*watermark) {
+			watermark = &updatedAt
+		}
+	}
+
+	if watermark != nil {
+		err = db.SetWatermark(*watermark)
+		if err != nil {
+			return 0, fmt.Errorf("error saving watermark: %w", err)
+		}
+	}
+	err = db.SetQueryHash(hashQuery(ordersQueryShape))
+	if err != nil {
+		return 0, fmt.Errorf("error saving query hash: %w", err)
+	}
+
+	return len(orders), nil
+}
+
+type OrderDBServiceOp struct {
+	db *OrderDB
+}
+
+var _ OrderService = &OrderDBServiceOp{}
+
+// ListCreatedBetween returns the stored orders the bulk query would have returned for the range, and the ones with
+// transactions or refunds in it that were updated again since.
+func (s *OrderDBServiceOp) ListCreatedBetween(ctx context.Context, from, to time.Time, useCached bool) ([]*model.Order, error) {
+	orders, err := s.db.All()
+	if err != nil {
+		return []*model.Order{}, fmt.Errorf("error reading orders from database: %w", err)
+	}
+
+	res := []*model.Order{}
+	for _, o := range orders {
+		if len(FilterOrdersInRange([]*model.Order{o}, from, to)) > 0 || hasActivityInRange(o, from, to) {
+			res = append(res, o)
+		}
+	}
+	return res, nil
+}
+
+// hasActivityInRange tells if the order has transactions processed or refunds made within the range.
+func hasActivityInRange(o *model.Order, from, to time.Time) bool {
+	dates := []*string{}
+	for _, t := range o.Transactions {
+		dates = append(dates, t.ProcessedAt)
+	}
+	for _, r := range o.Refunds {
+		dates = append(dates, r.CreatedAt)
+	}
+	for _, d := range dates {
+		if d == nil {
+			continue
+		}
+		t, err := time.Parse(ISO8601Layout, *d)
+		if err == nil && isInPeriod(t, from, to) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *OrderDBServiceOp) ListUpdatedSince(ctx context.Context, since *time.Time) ([]*model.Order, error) {
+	orders, err := s.db.All()
+	if err != nil {
+		return []*model.Order{}, fmt.Errorf("error reading orders from database: %w", err)
+	}
+	if since == nil {
+		return orders, nil
+	}
+
+	res := []*model.Order{}
+	for _, o := range orders {
+		updatedAt, err := time.Parse(ISO8601Layout, o.UpdatedAt)
+		if err != nil {
+			return []*model.Order{}, fmt.Errorf("error parsing updated at time: %w", err)
+		}
+		if !updatedAt.Before(*since) {
+			res = append(res, o)
+		}
+	}
+	return res, nil
+}
+
+// Timezone returns the shop IANA timezone saved by the last sync, or an empty string if there's none.
+func (d *OrderDB) Timezone() (string, error) {
+	var res string
+	err := d.db.View(func(tx *bolt.Tx) error {
+		res = string(tx.Bucket(metaBucket).Get(timezoneKey))
+		return nil
+	})
+	return res, err
+}
+
+func (d *OrderDB) SetTimezone(tz string) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(timezoneKey, []byte(tz))
+	})
+}
@@ -0,0 +1,151 @@
+package shop
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop/shoptest"
+)
+
+func TestSyncOrders(t *testing.T) {
+	db, err := OpenOrderDB(filepath.Join(t.TempDir(), "orders.db"))
+	if err != nil {
+		t.Fatalf("OpenOrderDB(), err=%v", err)
+	}
+	defer db.Close()
+
+	s := &shoptest.OrderService{
+		Orders: []*model.Order{
+			{ID: "gid://shopify/Order/1", Name: "#1001", CreatedAt: "2020-04-01T10:00:00Z", UpdatedAt: "2020-04-01T10:00:00Z"},
+			{ID: "gid://shopify/Order/2", Name: "#1002", CreatedAt: "2020-04-02T10:00:00Z", UpdatedAt: "2020-04-03T10:00:00Z"},
+		},
+	}
+
+	n, err := SyncOrders(context.Background(), s, db)
+	if err != nil {
+		t.Fatalf("SyncOrders(), err=%v", err)
+	}
+	if n != 2 {
+		t.Errorf("SyncOrders() = %d, want 2", n)
+	}
+
+	s.Orders = []*model.Order{
+		{ID: "gid://shopify/Order/1", Name: "#1001-edited", CreatedAt: "2020-04-01T10:00:00Z", UpdatedAt: "2020-04-05T10:00:00Z"},
+	}
+	_, err = SyncOrders(context.Background(), s, db)
+	if err != nil {
+		t.Fatalf("SyncOrders(), err=%v", err)
+	}
+
+	if s.Since[0] != nil {
+		t.Errorf("first sync watermark = %v, want nil", s.Since[0])
+	}
+	if want := time.Date(2020, 4, 3, 10, 0, 0, 0, time.UTC); s.Since[1] == nil || !s.Since[1].Equal(want) {
+		t.Errorf("second sync watermark = %v, want %v", s.Since[1], want)
+	}
+
+	all, err := db.All()
+	if err != nil {
+		t.Fatalf("All(), err=%v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("All() = %d orders, want 2", len(all))
+	}
+	for _, o := range all {
+		if o.ID == "gid://shopify/Order/1" && o.Name != "#1001-edited" {
+			t.Errorf("order 1 name = %s, want the upserted #1001-edited", o.Name)
+		}
+	}
+
+	watermark, err := db.Watermark()
+	if err != nil {
+		t.Fatalf("Watermark(), err=%v", err)
+	}
+	if want := time.Date(2020, 4, 5, 10, 0, 0, 0, time.UTC); !watermark.Equal(want) {
+		t.Errorf("Watermark() = %v, want %v", watermark, want)
+	}
+
+	if err := db.SetQueryHash("stale"); err != nil {
+		t.Fatalf("SetQueryHash(), err=%v", err)
+	}
+	_, err = SyncOrders(context.Background(), s, db)
+	if err != nil {
+		t.Fatalf("SyncOrders(), err=%v", err)
+	}
+	if s.Since[2] != nil {
+		t.Errorf("sync after the query changed watermark = %v, want nil", s.Since[2])
+	}
+	if hash, _ := db.QueryHash(); hash != hashQuery(ordersQueryShape) {
+		t.Errorf("QueryHash() after sync = %s, want the current query's", hash)
+	}
+}
+
+func TestOrderDBServiceOpListCreatedBetween(t *testing.T) {
+	db, err := OpenOrderDB(filepath.Join(t.TempDir(), "orders.db"))
+	if err != nil {
+		t.Fatalf("OpenOrderDB(), err=%v", err)
+	}
+	defer db.Close()
+
+	// A March order refunded in April and edited in May, and a March order.
+	err = db.Upsert([]*model.Order{
+		{
+			ID: "gid://shopify/Order/1", CreatedAt: "2020-03-20T10:00:00Z", UpdatedAt: "2020-05-02T10:00:00Z", ProcessedAt: "2020-03-20T10:00:00Z",
+			Transactions: []model.OrderTransaction{{ProcessedAt: model.NewString("2020-04-05T12:00:00Z"), Kind: model.OrderTransactionKindRefund}},
+		},
+		{ID: "gid://shopify/Order/2", CreatedAt: "2020-03-21T10:00:00Z", UpdatedAt: "2020-03-21T10:00:00Z", ProcessedAt: "2020-03-21T10:00:00Z"},
+	})
+	if err != nil {
+		t.Fatalf("Upsert(), err=%v", err)
+	}
+
+	s := &OrderDBServiceOp{db: db}
+	from := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 4, 30, 23, 59, 59, 0, time.UTC)
+	orders, err := s.ListCreatedBetween(context.Background(), from, to, false)
+	if err != nil {
+		t.Fatalf("ListCreatedBetween(), err=%v", err)
+	}
+	if len(orders) != 1 || orders[0].ID != "gid://shopify/Order/1" {
+		t.Errorf("ListCreatedBetween() = %v, want the order refunded in April", orders)
+	}
+}
+
+func TestOfflineClientLocation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.db")
+	c, err := NewOfflineClient(path)
+	if err != nil {
+		t.Fatalf("NewOfflineClient(), err=%v", err)
+	}
+	defer c.Close()
+
+	loc, err := c.Location(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Location(), err=%v", err)
+	}
+	if loc != time.UTC {
+		t.Errorf("Location() without a synced timezone = %s, want UTC", loc)
+	}
+
+	if err := c.db.SetTimezone("Europe/London"); err != nil {
+		t.Fatalf("SetTimezone(), err=%v", err)
+	}
+	loc, err = c.Location(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Location(), err=%v", err)
+	}
+	if loc.String() != "Europe/London" {
+		t.Errorf("Location() = %s, want Europe/London", loc)
+	}
+
+	loc, err = c.Location(context.Background(), "America/New_York")
+	if err != nil {
+		t.Fatalf("Location(), err=%v", err)
+	}
+	if loc.String() != "America/New_York" {
+		t.Errorf("Location(America/New_York) = %s, want America/New_York", loc)
+	}
+}
@@ -0,0 +1,226 @@
+package shop
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/shopspring/decimal"
+)
+
+// LineItemSales is what a line item sold, in the report currency.
+type LineItemSales struct {
+	Ordered   int
+	Fulfilled int
+	Refunded  int
+
+	// Gross is the line total after discounts, Refunds is the part of it refunded.
+	Gross   decimal.Decimal
+	Refunds decimal.Decimal
+}
+
+func (s LineItemSales) Net() decimal.Decimal {
+	return s.Gross.Sub(s.Refunds)
+}
+
+// CalcLineItemSales returns the line item units and revenue. Refunded units are the ones removed from the order
+// and are refunded at the discounted unit price. Amounts are converted at the date the order was placed.
+func CalcLineItemSales(o *model.Order, li *model.LineItem, opts CalcOptions) (*LineItemSales, error) {
+	res := &LineItemSales{
+		Ordered:   li.Quantity,
+		Fulfilled: li.Quantity - li.UnfulfilledQuantity,
+		Refunded:  li.Quantity - li.CurrentQuantity,
+	}
+	if res.Refunded < 0 {
+		res.Refunded = 0
+	}
+
+	if li.DiscountedTotalSet == nil {
+		return res, nil
+	}
+	total, err := ParseMoney(opts.money(li.DiscountedTotalSet))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing line item total: %w", err)
+	}
+	createdAt, err := time.Parse(ISO8601Layout, o.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing created at time: %w", err)
+	}
+	res.Gross, err = opts.Convert(*total, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("error converting line item total: %w", err)
+	}
+
+	if res.Ordered > 0 && res.Refunded > 0 {
+		res.Refunds = res.Gross.Mul(decimal.NewFromInt(int64(res.Refunded))).Div(decimal.NewFromInt(int64(res.Ordered))).Round(2)
+	}
+
+	return res, nil
+}
+
+// IsOrderCreatedInPeriod tells if the order was placed in the period.
+func IsOrderCreatedInPeriod(o *model.Order, from, to time.Time) (bool, error) {
+	createdAt, err := time.Parse(ISO8601Layout, o.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("error parsing created at time: %w", err)
+	}
+	return isInPeriod(createdAt, from, to), nil
+}
+
+// LineItemRevenue is a line item's share of the order sales and refunds recognised in a period.
+type LineItemRevenue struct {
+	LineItem *model.LineItem
+	Sales    decimal.Decimal
+	Refunds  decimal.Decimal
+	// RefundedQuantity is the units of the line refunded in the period.
+	RefundedQuantity int
+}
+
+// CalcLineItemRevenue apportions the order sales and refunds in the period to its line items, so the shares add up
+// to the order turnover. Sales are split by the discounted line totals. Refunds are split by the lines each refund
+// made in the period refunded, or by the line totals when a refund has no lines (e.g. a shipping refund)
+// or the order refunds weren't fetched.
+func CalcLineItemRevenue(o *model.Order, from, to time.Time, opts CalcOptions) ([]LineItemRevenue, error) {
+	if o.LineItems == nil || len(o.LineItems.Edges) == 0 {
+		return []LineItemRevenue{}, nil
+	}
+
+	sales, err := CalcOrderSales(o, from, to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error getting sales total: %w", err)
+	}
+	refunds, err := CalcOrderRefunds(o, from, to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error getting refunds total: %w", err)
+	}
+
+	salesWeights := make([]decimal.Decimal, len(o.LineItems.Edges))
+	for i, edge := range o.LineItems.Edges {
+		if edge.Node == nil {
+			continue
+		}
+		// Weights are ratios within the order, so the line totals are left in the order currency.
+		s, err := CalcLineItemSales(o, edge.Node, CalcOptions{})
+		if err != nil {
+			return nil, err
+		}
+		salesWeights[i] = s.Gross
+	}
+	refundWeights, refundedQuantities, err := calcRefundedLines(o, salesWeights, from, to, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	salesShares := apportion(*sales, salesWeights)
+	refundShares := apportion(*refunds, salesWeights)
+	if !sum(refundWeights).IsZero() {
+		refundShares = apportion(*refunds, refundWeights)
+	}
+
+	res := make([]LineItemRevenue, 0, len(o.LineItems.Edges))
+	for i, edge := range o.LineItems.Edges {
+		if edge.Node == nil {
+			continue
+		}
+		res = append(res, LineItemRevenue{
+			LineItem:         edge.Node,
+			Sales:            salesShares[i],
+			Refunds:          refundShares[i],
+			RefundedQuantity: refundedQuantities[i],
+		})
+	}
+	return res, nil
+}
+
+// calcRefundedLines splits the refunds made in the period by the lines each of them refunded, or by the weights
+// when a refund has no lines, and adds up the shares and refunded units of each line item.
+func calcRefundedLines(o *model.Order, weights []decimal.Decimal, from, to time.Time, opts CalcOptions) ([]decimal.Decimal, []int, error) {
+	res := make([]decimal.Decimal, len(o.LineItems.Edges))
+	quantities := make([]int, len(o.LineItems.Edges))
+	for _, r := range o.Refunds {
+		if r.CreatedAt == nil {
+			continue
+		}
+		createdAt, err := time.Parse(ISO8601Layout, *r.CreatedAt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing refund created at time: %w", err)
+		}
+		if !isInPeriod(createdAt, from, to) {
+			continue
+		}
+
+		lines := make([]decimal.Decimal, len(res))
+		if r.RefundLineItems != nil {
+			for _, edge := range r.RefundLineItems.Edges {
+				if edge.Node == nil || edge.Node.LineItem == nil || edge.Node.SubtotalSet == nil {
+					continue
+				}
+				i := lineItemIndex(o, edge.Node.LineItem.ID)
+				if i < 0 {
+					continue
+				}
+				subtotal, err := ParseMoney(opts.money(edge.Node.SubtotalSet))
+				if err != nil {
+					return nil, nil, fmt.Errorf("error parsing refunded line subtotal: %w", err)
+				}
+				lines[i] = lines[i].Add(subtotal.Amount)
+				quantities[i] += edge.Node.Quantity
+			}
+		}
+
+		total := sum(lines)
+		if r.TotalRefundedSet != nil {
+			m, err := ParseMoney(opts.money(r.TotalRefundedSet))
+			if err != nil {
+				return nil, nil, fmt.Errorf("error parsing refund total: %w", err)
+			}
+			total = m.Amount
+		}
+		if sum(lines).IsZero() {
+			lines = weights
+		}
+		for i, share := range apportion(total, lines) {
+			res[i] = res[i].Add(share)
+		}
+	}
+	return res, quantities, nil
+}
+
+func lineItemIndex(o *model.Order, id string) int {
+	for i, edge := range o.LineItems.Edges {
+		if edge.Node != nil && edge.Node.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// apportion splits the total in proportion to the weights, or evenly if they're all zero.
+// The last share takes the remainder so the shares add up to the total exactly.
+func apportion(total decimal.Decimal, weights []decimal.Decimal) []decimal.Decimal {
+	res := make([]decimal.Decimal, len(weights))
+	if len(weights) == 0 {
+		return res
+	}
+
+	totalWeight := sum(weights)
+	rest := total
+	for i, w := range weights[:len(weights)-1] {
+		if totalWeight.IsZero() {
+			res[i] = total.Div(decimal.NewFromInt(int64(len(weights))))
+		} else {
+			res[i] = total.Mul(w).Div(totalWeight)
+		}
+		rest = rest.Sub(res[i])
+	}
+	res[len(res)-1] = rest
+	return res
+}
+
+func sum(values []decimal.Decimal) decimal.Decimal {
+	res := decimal.Zero
+	for _, v := range values {
+		res = res.Add(v)
+	}
+	return res
+}
@@ -0,0 +1,141 @@
+package shop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestCalcLineItemSales(t *testing.T) {
+	o := &model.Order{
+		CreatedAt: time.Date(2020, 4, 1, 10, 30, 0, 0, time.UTC).Format(ISO8601Layout),
+	}
+	li := &model.LineItem{
+		Quantity:            3,
+		CurrentQuantity:     2,
+		UnfulfilledQuantity: 1,
+		DiscountedTotalSet: &model.MoneyBag{
+			ShopMoney: &model.MoneyV2{
+				Amount: null.StringFrom("25.00"),
+			},
+		},
+	}
+
+	got, err := CalcLineItemSales(o, li, CalcOptions{})
+	if err != nil {
+		t.Fatalf("CalcLineItemSales(), err=%v", err)
+	}
+	if got.Ordered != 3 || got.Fulfilled != 2 || got.Refunded != 1 {
+		t.Errorf("CalcLineItemSales() units = %d/%d/%d, want 3/2/1", got.Ordered, got.Fulfilled, got.Refunded)
+	}
+	if want := decimal.RequireFromString("25.00"); !got.Gross.Equal(want) {
+		t.Errorf("CalcLineItemSales() gross = %s, want %s", got.Gross, want)
+	}
+	if want := decimal.RequireFromString("8.33"); !got.Refunds.Equal(want) {
+		t.Errorf("CalcLineItemSales() refunds = %s, want %s", got.Refunds, want)
+	}
+	if want := decimal.RequireFromString("16.67"); !got.Net().Equal(want) {
+		t.Errorf("CalcLineItemSales() net = %s, want %s", got.Net(), want)
+	}
+}
+
+func TestCalcLineItemRevenue(t *testing.T) {
+	from := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 4, 30, 23, 59, 59, 0, time.UTC)
+
+	newTransaction := func(kind model.OrderTransactionKind, amount string, day int) model.OrderTransaction {
+		return model.OrderTransaction{
+			ProcessedAt: model.NewString(time.Date(2020, 4, day, 10, 0, 0, 0, time.UTC).Format(ISO8601Layout)),
+			Kind:        kind,
+			Status:      model.OrderTransactionStatusSuccess,
+			AmountSet: &model.MoneyBag{
+				ShopMoney: &model.MoneyV2{
+					Amount: null.StringFrom(amount),
+				},
+			},
+		}
+	}
+	newLineItem := func(id, total string, quantity, currentQuantity int) model.LineItemEdge {
+		return model.LineItemEdge{
+			Node: &model.LineItem{
+				ID:              id,
+				Quantity:        quantity,
+				CurrentQuantity: currentQuantity,
+				DiscountedTotalSet: &model.MoneyBag{
+					ShopMoney: &model.MoneyV2{
+						Amount: null.StringFrom(total),
+					},
+				},
+			},
+		}
+	}
+	newRefund := func(createdAt time.Time, total string, lines map[string]string) model.Refund {
+		r := model.Refund{
+			CreatedAt:        model.NewString(createdAt.Format(ISO8601Layout)),
+			TotalRefundedSet: &model.MoneyBag{ShopMoney: &model.MoneyV2{Amount: null.StringFrom(total)}},
+			RefundLineItems:  &model.RefundLineItemConnection{},
+		}
+		for id, subtotal := range lines {
+			r.RefundLineItems.Edges = append(r.RefundLineItems.Edges, model.RefundLineItemEdge{
+				Node: &model.RefundLineItem{
+					LineItem:    &model.LineItem{ID: id},
+					Quantity:    1,
+					SubtotalSet: &model.MoneyBag{ShopMoney: &model.MoneyV2{Amount: null.StringFrom(subtotal)}},
+				},
+			})
+		}
+		return r
+	}
+
+	// Two lines plus 5.00 shipping. The first line and the shipping are refunded in the period,
+	// the second line after it.
+	o := &model.Order{
+		CreatedAt: time.Date(2020, 4, 10, 9, 0, 0, 0, time.UTC).Format(ISO8601Layout),
+		Transactions: []model.OrderTransaction{
+			newTransaction(model.OrderTransactionKindSale, "35.00", 10),
+			newTransaction(model.OrderTransactionKindRefund, "20.00", 12),
+			newTransaction(model.OrderTransactionKindRefund, "5.00", 20),
+		},
+		LineItems: &model.LineItemConnection{
+			Edges: []model.LineItemEdge{
+				newLineItem("gid://shopify/LineItem/1", "20.00", 1, 0),
+				{},
+				newLineItem("gid://shopify/LineItem/2", "10.00", 1, 0),
+			},
+		},
+		Refunds: []model.Refund{
+			newRefund(time.Date(2020, 4, 12, 10, 0, 0, 0, time.UTC), "20.00", map[string]string{"gid://shopify/LineItem/1": "20.00"}),
+			newRefund(time.Date(2020, 4, 20, 10, 0, 0, 0, time.UTC), "5.00", nil),
+			newRefund(time.Date(2020, 5, 3, 10, 0, 0, 0, time.UTC), "10.00", map[string]string{"gid://shopify/LineItem/2": "10.00"}),
+		},
+	}
+
+	got, err := CalcLineItemRevenue(o, from, to, CalcOptions{})
+	if err != nil {
+		t.Fatalf("CalcLineItemRevenue(), err=%v", err)
+	}
+
+	// The line item without a node is left out
+	if len(got) != 2 {
+		t.Fatalf("CalcLineItemRevenue() = %d lines, want 2", len(got))
+	}
+	wantSales := []string{"23.333333333333333333", "11.666666666666666667"}
+	wantRefunds := []string{"23.33", "1.67"}
+	totalSales, totalRefunds := decimal.Zero, decimal.Zero
+	for i, line := range got {
+		if !line.Sales.Round(2).Equal(decimal.RequireFromString(wantSales[i]).Round(2)) {
+			t.Errorf("CalcLineItemRevenue() line %d sales = %s, want %s", i, line.Sales, wantSales[i])
+		}
+		if !line.Refunds.Round(2).Equal(decimal.RequireFromString(wantRefunds[i])) {
+			t.Errorf("CalcLineItemRevenue() line %d refunds = %s, want %s", i, line.Refunds, wantRefunds[i])
+		}
+		totalSales = totalSales.Add(line.Sales)
+		totalRefunds = totalRefunds.Add(line.Refunds)
+	}
+	if !totalSales.Equal(decimal.RequireFromString("35.00")) || !totalRefunds.Equal(decimal.RequireFromString("25.00")) {
+		t.Errorf("CalcLineItemRevenue() shares add up to %s/%s, want 35.00/25.00", totalSales, totalRefunds)
+	}
+}
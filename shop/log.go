@@ -0,0 +1,13 @@
+package shop
+
+// Logger receives the progress messages of the order queries, syncs and reports, e.g. a logrus logger.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// Log is what the library packages log to. Nothing is logged unless it's set.
+var Log Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(format string, args ...interface{}) {}
@@ -0,0 +1,92 @@
+package shop
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/shopspring/decimal"
+)
+
+type Money struct {
+	Amount   decimal.Decimal
+	Currency model.CurrencyCode
+}
+
+func ParseMoney(m *model.MoneyV2) (*Money, error) {
+	if m == nil {
+		return &Money{Amount: decimal.Zero}, nil
+	}
+
+	amount, err := decimal.NewFromString(m.Amount.ValueOrZero())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing amount: %w", err)
+	}
+	return &Money{Amount: amount, Currency: m.CurrencyCode}, nil
+}
+
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Amount.StringFixed(2), m.Currency)
+}
+
+// Convert returns the amount in the report currency, exchanged at the rate on the date.
+func (opts CalcOptions) Convert(m Money, on time.Time) (decimal.Decimal, error) {
+	if opts.Currency == "" || m.Currency == "" || m.Currency == opts.Currency {
+		return m.Amount, nil
+	}
+	if opts.Rates == nil {
+		return decimal.Zero, fmt.Errorf("no exchange rates to convert %s to %s", m.Currency, opts.Currency)
+	}
+
+	rate, err := opts.Rates.Rate(m.Currency, opts.Currency, on)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("error getting exchange rate: %w", err)
+	}
+	return m.Amount.Mul(rate).Round(2), nil
+}
+
+// GetOrdersCurrencies returns the currencies of the order transactions in the money the options add up.
+func GetOrdersCurrencies(orders []*model.Order, opts CalcOptions) []model.CurrencyCode {
+	seen := map[model.CurrencyCode]bool{}
+	for _, o := range orders {
+		for _, t := range o.Transactions {
+			m := opts.money(t.AmountSet)
+			if m == nil || m.CurrencyCode == "" {
+				continue
+			}
+			seen[m.CurrencyCode] = true
+		}
+	}
+
+	res := []model.CurrencyCode{}
+	for c := range seen {
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+// ResolveCurrency sets the report currency to the orders currency unless it's set already.
+// Orders in mixed currencies can't be added up without a report currency to convert them to.
+func ResolveCurrency(orders []*model.Order, opts CalcOptions) (CalcOptions, error) {
+	if opts.Currency != "" {
+		return opts, nil
+	}
+
+	currencies := GetOrdersCurrencies(orders, opts)
+	switch len(currencies) {
+	case 0:
+		return opts, nil
+	case 1:
+		opts.Currency = currencies[0]
+		return opts, nil
+	default:
+		names := []string{}
+		for _, c := range currencies {
+			names = append(names, string(c))
+		}
+		return opts, fmt.Errorf("orders are in mixed currencies (%s), set the report currency and exchange rates", strings.Join(names, ", "))
+	}
+}
@@ -0,0 +1,112 @@
+package shop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/fx"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestCalcTotalTurnoverMixedCurrencies(t *testing.T) {
+	from := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 4, 30, 23, 59, 59, 0, time.UTC)
+
+	newSale := func(amount string, currency model.CurrencyCode) model.OrderTransaction {
+		return model.OrderTransaction{
+			ProcessedAt: model.NewString(time.Date(2020, 4, 10, 10, 0, 0, 0, time.UTC).Format(ISO8601Layout)),
+			Kind:        model.OrderTransactionKindSale,
+			Status:      model.OrderTransactionStatusSuccess,
+			AmountSet: &model.MoneyBag{
+				ShopMoney: &model.MoneyV2{
+					Amount:       null.StringFrom(amount),
+					CurrencyCode: currency,
+				},
+			},
+		}
+	}
+	orders := []*model.Order{
+		{Transactions: []model.OrderTransaction{newSale("10.00", model.CurrencyCodeGbp)}},
+		{Transactions: []model.OrderTransaction{newSale("11.00", model.CurrencyCodeEur)}},
+	}
+
+	_, err := ResolveCurrency(orders, CalcOptions{})
+	if err == nil {
+		t.Errorf("ResolveCurrency() of mixed currencies, err=nil, want an error")
+	}
+
+	_, err = CalcTotalTurnover(orders, from, to, CalcOptions{Currency: model.CurrencyCodeGbp})
+	if err == nil {
+		t.Errorf("CalcTotalTurnover() without rates, err=nil, want an error")
+	}
+
+	rates := fx.NewTableRates(model.CurrencyCodeGbp)
+	rates.Add(model.CurrencyCodeEur, decimal.RequireFromString("1.1"), from, to)
+
+	got, err := CalcTotalTurnover(orders, from, to, CalcOptions{Currency: model.CurrencyCodeGbp, Rates: rates})
+	if err != nil {
+		t.Fatalf("CalcTotalTurnover(), err=%v", err)
+	}
+	if want := decimal.RequireFromString("20.00"); !got.Equal(want) {
+		t.Errorf("CalcTotalTurnover() = %s, want %s", got.String(), want.String())
+	}
+}
+
+func TestCalcTotalTurnoverPresentment(t *testing.T) {
+	from := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 4, 30, 23, 59, 59, 0, time.UTC)
+
+	newSale := func(shopAmount, presentmentAmount string, presentment model.CurrencyCode) model.OrderTransaction {
+		return model.OrderTransaction{
+			ProcessedAt: model.NewString(time.Date(2020, 4, 10, 10, 0, 0, 0, time.UTC).Format(ISO8601Layout)),
+			Kind:        model.OrderTransactionKindSale,
+			Status:      model.OrderTransactionStatusSuccess,
+			AmountSet: &model.MoneyBag{
+				ShopMoney: &model.MoneyV2{
+					Amount:       null.StringFrom(shopAmount),
+					CurrencyCode: model.CurrencyCodeGbp,
+				},
+				PresentmentMoney: &model.MoneyV2{
+					Amount:       null.StringFrom(presentmentAmount),
+					CurrencyCode: presentment,
+				},
+			},
+		}
+	}
+	orders := []*model.Order{
+		{Transactions: []model.OrderTransaction{newSale("10.00", "11.00", model.CurrencyCodeEur)}},
+		{Transactions: []model.OrderTransaction{newSale("20.00", "22.00", model.CurrencyCodeEur)}},
+	}
+
+	opts, err := ResolveCurrency(orders, CalcOptions{})
+	if err != nil {
+		t.Fatalf("ResolveCurrency(), err=%v", err)
+	}
+	if opts.Currency != model.CurrencyCodeGbp {
+		t.Errorf("ResolveCurrency() currency = %s, want %s", opts.Currency, model.CurrencyCodeGbp)
+	}
+	got, err := CalcTotalTurnover(orders, from, to, opts)
+	if err != nil {
+		t.Fatalf("CalcTotalTurnover(), err=%v", err)
+	}
+	if want := decimal.RequireFromString("30.00"); !got.Equal(want) {
+		t.Errorf("CalcTotalTurnover() in shop money = %s, want %s", got.String(), want.String())
+	}
+
+	opts, err = ResolveCurrency(orders, CalcOptions{Presentment: true})
+	if err != nil {
+		t.Fatalf("ResolveCurrency() of presentment money, err=%v", err)
+	}
+	if opts.Currency != model.CurrencyCodeEur {
+		t.Errorf("ResolveCurrency() presentment currency = %s, want %s", opts.Currency, model.CurrencyCodeEur)
+	}
+	got, err = CalcTotalTurnover(orders, from, to, opts)
+	if err != nil {
+		t.Fatalf("CalcTotalTurnover() of presentment money, err=%v", err)
+	}
+	if want := decimal.RequireFromString("33.00"); !got.Equal(want) {
+		t.Errorf("CalcTotalTurnover() in presentment money = %s, want %s", got.String(), want.String())
+	}
+}
@@ -0,0 +1,83 @@
+package shop
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/fx"
+)
+
+// Basis is the accounting basis sales are recognised on.
+type Basis string
+
+const (
+	// BasisCash recognises payments and refunds when they're processed.
+	BasisCash Basis = "cash"
+	// BasisAccrual recognises sales when the order is placed and refunds when they're processed.
+	BasisAccrual Basis = "accrual"
+)
+
+func ParseBasis(s string) (Basis, error) {
+	switch Basis(s) {
+	case "", BasisCash:
+		return BasisCash, nil
+	case BasisAccrual:
+		return BasisAccrual, nil
+	default:
+		return "", fmt.Errorf("unknown accounting basis `%s`", s)
+	}
+}
+
+// CalcOptions configures the order calculations. The zero value calculates on the cash basis
+// and adds up shop money as is, with periods in UTC.
+type CalcOptions struct {
+	Basis Basis
+
+	// Location is the timezone periods are in, usually the shop's.
+	Location *time.Location
+
+	// Currency is the report currency, shop money in other currencies is converted with Rates.
+	Currency model.CurrencyCode
+	Rates    fx.RateProvider
+
+	// Presentment adds up the amounts in the currency customers paid in instead of shop money.
+	Presentment bool
+}
+
+// money returns the amount of the money set the options add up: presentment money if it's selected
+// and the set has it, shop money otherwise.
+func (opts CalcOptions) money(set *model.MoneyBag) *model.MoneyV2 {
+	if set == nil {
+		return nil
+	}
+	if opts.Presentment && set.PresentmentMoney != nil {
+		return set.PresentmentMoney
+	}
+	return set.ShopMoney
+}
+
+// Describe explains how orders are recognised and converted with the options, for report records.
+func (opts CalcOptions) Describe() []string {
+	res := []string{}
+	if opts.Basis == BasisAccrual {
+		res = append(res, "Accrual basis: sales are the successful payments of the orders placed in the period, refunds the successful refunds processed in it.")
+	} else {
+		res = append(res, "Cash basis: sales and refunds are the successful payments and refunds processed in the period.")
+	}
+	res = append(res, "Test transactions, failed or pending ones and authorisations are left out. Turnover is the sales less the refunds, including VAT.")
+
+	loc := "UTC"
+	if opts.Location != nil {
+		loc = opts.Location.String()
+	}
+	res = append(res, fmt.Sprintf("Period dates start and end at midnight in %s.", loc))
+
+	if opts.Presentment {
+		res = append(res, "Amounts are in the presentment currency customers paid in rather than the shop currency.")
+	}
+	if opts.Currency != "" && opts.Rates != nil {
+		res = append(res, fmt.Sprintf("Amounts in other currencies are converted to %s at the exchange rate for the month they're processed in.", opts.Currency))
+	}
+	return res
+}
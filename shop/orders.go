@@ -6,53 +6,24 @@ import (
 	"strings"
 	"time"
 
-	diskstore "github.com/r0busta/go-object-store/disk"
 	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
 	"github.com/shopspring/decimal"
-	log "github.com/sirupsen/logrus"
 )
 
+// OrderService lists the orders reports are computed from.
 type OrderService interface {
-	ListCreatedBetween(from, to time.Time, useCached bool) ([]*model.Order, error)
+	ListCreatedBetween(ctx context.Context, from, to time.Time, useCached bool) ([]*model.Order, error)
+	ListUpdatedSince(ctx context.Context, since *time.Time) ([]*model.Order, error)
 }
 
 type OrderServiceOp struct {
 	client *Client
-	cache  *diskstore.Store
+	cache  *OrderCache
 }
 
 var _ OrderService = &OrderServiceOp{}
 
-func (s *OrderServiceOp) ListCreatedBetween(from, to time.Time, useCached bool) ([]*model.Order, error) {
-	isCacheValid := false
-	if useCached {
-		isCacheValid = s.cache.FileExists()
-	}
-
-	if useCached && isCacheValid {
-		orders := []*model.Order{}
-		err := s.cache.Read(&orders)
-		if err != nil {
-			return []*model.Order{}, fmt.Errorf("error reading orders from cache: %s", err)
-		}
-		return orders, err
-	}
-
-	orders, err := s.listCreatedBetween(from, to)
-	if err != nil {
-		return []*model.Order{}, fmt.Errorf("error listing orders: %s", err)
-	}
-	err = s.cache.Write(orders)
-	if err != nil {
-		return []*model.Order{}, fmt.Errorf("error caching orders: %s", err)
-	}
-	return orders, err
-}
-
-func (s *OrderServiceOp) listCreatedBetween(from, to time.Time) ([]*model.Order, error) {
-	log.Printf("Getting orders in the range %s and %s", from.Format("Jan 2, 2006"), to.Format("Jan 2, 2006"))
-
-	query := `
+const ordersQuery = `
 	{
 		orders(query: "$query") {
 			edges {
@@ -60,23 +31,37 @@ func (s *OrderServiceOp) listCreatedBetween(from, to time.Time) ([]*model.Order,
 					id
 					name
 					createdAt
+					updatedAt
+					processedAt
 					totalPriceSet {
 						shopMoney {
 							amount
 							currencyCode
 						}
+						presentmentMoney {
+							amount
+							currencyCode
+						}
 					}
 					totalRefundedSet {
 						shopMoney {
 							amount
 							currencyCode
 						}
+						presentmentMoney {
+							amount
+							currencyCode
+						}
 					}
 					currentTotalTaxSet {
 						shopMoney {
 							amount
 							currencyCode
 						}
+						presentmentMoney {
+							amount
+							currencyCode
+						}
 					}
 					taxLines {
 						rate
@@ -91,22 +76,99 @@ func (s *OrderServiceOp) listCreatedBetween(from, to time.Time) ([]*model.Order,
 								amount
 								currencyCode
 							}
+							presentmentMoney {
+								amount
+								currencyCode
+							}
 						}
 					}
 					shippingAddress{
 						countryCodeV2
 					}
+					app{
+						name
+					}
+					discountCodes
+					customer{
+						id
+						displayName
+					}
 					lineItems{
 						edges{
 							node{
 								id
+								title
+								sku
 								product{
 									id
+									title
+									productType
 									tags
 								}
+								variant{
+									id
+									title
+									sku
+								}
 								vendor
 								quantity
+								currentQuantity
 								unfulfilledQuantity
+								discountedTotalSet {
+									shopMoney {
+										amount
+										currencyCode
+									}
+									presentmentMoney {
+										amount
+										currencyCode
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	`
+
+// orderRefundsQuery gets the refunds of orders with the lines they refunded. Bulk queries can't select connections
+// inside lists, so they're queried for the refunded orders once the bulk operation is done.
+const orderRefundsQuery = `
+	query ($ids: [ID!]!) {
+		nodes(ids: $ids) {
+			... on Order {
+				id
+				refunds {
+					createdAt
+					totalRefundedSet {
+						shopMoney {
+							amount
+							currencyCode
+						}
+						presentmentMoney {
+							amount
+							currencyCode
+						}
+					}
+					refundLineItems(first: 100) {
+						edges {
+							node {
+								lineItem {
+									id
+								}
+								quantity
+								subtotalSet {
+									shopMoney {
+										amount
+										currencyCode
+									}
+									presentmentMoney {
+										amount
+										currencyCode
+									}
+								}
 							}
 						}
 					}
@@ -115,43 +177,167 @@ func (s *OrderServiceOp) listCreatedBetween(from, to time.Time) ([]*model.Order,
 		}
 	}
 	`
-	query = strings.ReplaceAll(query, "$query", fmt.Sprintf(`
+
+// ordersQueryShape is what orders are fetched with, for the cache and the database to tell when it changed.
+const ordersQueryShape = ordersQuery + orderRefundsQuery
+
+// refundsBatchSize is the number of orders to get the refunds of per query.
+const refundsBatchSize = 50
+
+func (s *OrderServiceOp) ListCreatedBetween(ctx context.Context, from, to time.Time, useCached bool) ([]*model.Order, error) {
+	if useCached {
+		orders, meta, err := s.cache.Lookup(from, to, ordersQueryShape)
+		if err != nil {
+			return []*model.Order{}, fmt.Errorf("error reading orders from cache: %w", err)
+		}
+		if orders != nil {
+			Log.Printf("Using orders cached on %s for the range %s and %s", meta.FetchedAt.Format(time.RFC3339), meta.From.Format("Jan 2, 2006"), meta.To.Format("Jan 2, 2006"))
+			return orders, nil
+		}
+		Log.Printf("No cached orders cover the requested range, fetching them")
+	}
+
+	fetchedAt := time.Now()
+	orders, err := s.listCreatedBetween(ctx, from, to)
+	if err != nil {
+		return []*model.Order{}, fmt.Errorf("error listing orders: %w", err)
+	}
+	err = s.cache.Save(from, to, ordersQueryShape, orders, fetchedAt)
+	if err != nil {
+		return []*model.Order{}, fmt.Errorf("error caching orders: %w", err)
+	}
+	return orders, err
+}
+
+func (s *OrderServiceOp) listCreatedBetween(ctx context.Context, from, to time.Time) ([]*model.Order, error) {
+	Log.Printf("Getting orders in the range %s and %s", from.Format("Jan 2, 2006"), to.Format("Jan 2, 2006"))
+
+	return s.bulkQuery(ctx, fmt.Sprintf(`
 		(created_at:>='%[1]s' created_at:<='%[2]s')
 		OR (updated_at:>='%[1]s' updated_at:<='%[2]s')
-		OR (processed_at:>='%[1]s' processed_at:<='%[2]s')`, from.Format(ISO8601Layout), to.Format(ISO8601Layout)))
+		OR (processed_at:>='%[1]s' processed_at:<='%[2]s')`, from.UTC().Format(ISO8601Layout), to.UTC().Format(ISO8601Layout)))
+}
+
+func (s *OrderServiceOp) ListUpdatedSince(ctx context.Context, since *time.Time) ([]*model.Order, error) {
+	if since == nil {
+		Log.Printf("Getting all orders")
+		return s.bulkQuery(ctx, "")
+	}
+
+	Log.Printf("Getting orders updated since %s", since.Format(time.RFC3339))
+	return s.bulkQuery(ctx, fmt.Sprintf("updated_at:>='%s'", since.UTC().Format(ISO8601Layout)))
+}
+
+func (s *OrderServiceOp) bulkQuery(ctx context.Context, filter string) ([]*model.Order, error) {
+	query := strings.ReplaceAll(ordersQuery, "$query", filter)
 	var orders []*model.Order
 
-	err := s.client.shopifyClient.BulkOperation.BulkQuery(context.Background(), query, &orders)
+	err := s.client.shopifyClient.BulkOperation.BulkQuery(ctx, query, &orders)
 	if err != nil {
+		// Bulk operations without results complete with no result URL, which the client reports as an error
+		op, opErr := s.client.shopifyClient.BulkOperation.GetCurrentBulkQuery(ctx)
+		if opErr == nil && op.Status == model.BulkOperationStatusCompleted && op.ObjectCount == "0" {
+			return []*model.Order{}, nil
+		}
 		return nil, err
 	}
+
+	err = s.getRefunds(ctx, orders)
+	if err != nil {
+		return nil, fmt.Errorf("error getting refunds: %w", err)
+	}
 	return orders, nil
 }
 
-func GetTransactionAmount(t model.OrderTransaction) (*decimal.Decimal, error) {
+// getRefunds sets the refunds of the orders with refund transactions.
+func (s *OrderServiceOp) getRefunds(ctx context.Context, orders []*model.Order) error {
+	refunded := []*model.Order{}
+	for _, o := range orders {
+		for _, t := range o.Transactions {
+			if t.Kind == model.OrderTransactionKindRefund {
+				refunded = append(refunded, o)
+				break
+			}
+		}
+	}
+
+	for start := 0; start < len(refunded); start += refundsBatchSize {
+		end := start + refundsBatchSize
+		if end > len(refunded) {
+			end = len(refunded)
+		}
+		batch := refunded[start:end]
+
+		ids := make([]string, len(batch))
+		for i, o := range batch {
+			ids[i] = o.ID
+		}
+		var out struct {
+			Nodes []*struct {
+				ID      string         `json:"id"`
+				Refunds []model.Refund `json:"refunds"`
+			} `json:"nodes"`
+		}
+		err := s.client.shopifyClient.GraphQLClient().QueryString(ctx, orderRefundsQuery, map[string]interface{}{"ids": ids}, &out)
+		if err != nil {
+			return err
+		}
+		for i, node := range out.Nodes {
+			if node != nil && i < len(batch) {
+				batch[i].Refunds = node.Refunds
+			}
+		}
+	}
+	return nil
+}
+
+// FilterOrdersInRange keeps the orders the bulk query would have returned for the range,
+// i.e. created, updated or processed within it.
+func FilterOrdersInRange(orders []*model.Order, from, to time.Time) []*model.Order {
+	res := []*model.Order{}
+	for _, o := range orders {
+		for _, ts := range []string{o.CreatedAt, o.UpdatedAt, o.ProcessedAt} {
+			t, err := time.Parse(ISO8601Layout, ts)
+			if err != nil {
+				continue
+			}
+			if isInPeriod(t, from, to) {
+				res = append(res, o)
+				break
+			}
+		}
+	}
+	return res
+}
+
+var maxTime = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+func isInPeriod(t, from, to time.Time) bool {
+	return t.After(from) && t.Before(to) || t.Equal(from) || t.Equal(to)
+}
+
+func GetTransactionAmount(t model.OrderTransaction, opts CalcOptions) (*Money, error) {
 	if t.Status != model.OrderTransactionStatusSuccess {
-		return &decimal.Zero, nil
+		return &Money{Amount: decimal.Zero}, nil
 	}
 
 	switch t.Kind {
-	case model.OrderTransactionKindSale:
-		d, err := decimal.NewFromString(t.AmountSet.ShopMoney.Amount.String)
-		if err != nil {
-			return nil, fmt.Errorf("error: %s", err)
+	case model.OrderTransactionKindSale, model.OrderTransactionKindRefund:
+		if t.AmountSet == nil {
+			return nil, fmt.Errorf("error: no amount set")
 		}
-		return &d, nil
-	case model.OrderTransactionKindRefund:
-		d, err := decimal.NewFromString(t.AmountSet.ShopMoney.Amount.String)
+		m, err := ParseMoney(opts.money(t.AmountSet))
 		if err != nil {
-			return nil, fmt.Errorf("error: %s", err)
+			return nil, fmt.Errorf("error: %w", err)
 		}
-		return &d, nil
+		return m, nil
 	default:
-		return &decimal.Zero, nil
+		return &Money{Amount: decimal.Zero}, nil
 	}
 }
 
-func SumTransactions(transactions []model.OrderTransaction, kind model.OrderTransactionKind, from, to time.Time) (*decimal.Decimal, error) {
+// SumTransactions adds up the transactions of the kind processed in the period, in the report currency.
+func SumTransactions(transactions []model.OrderTransaction, kind model.OrderTransactionKind, from, to time.Time, opts CalcOptions) (*decimal.Decimal, error) {
 	var total decimal.Decimal
 
 	for _, t := range transactions {
@@ -161,45 +347,71 @@ func SumTransactions(transactions []model.OrderTransaction, kind model.OrderTran
 
 		processedAt, err := time.Parse(ISO8601Layout, *t.ProcessedAt)
 		if err != nil {
-			return nil, fmt.Errorf("error parsing processed at time: %s", err)
+			return nil, fmt.Errorf("error parsing processed at time: %w", err)
 		}
-		if processedAt.After(from) && processedAt.Before(to) || processedAt.Equal(from) || processedAt.Equal(to) {
-			amount, err := GetTransactionAmount(t)
+		if isInPeriod(processedAt, from, to) {
+			amount, err := GetTransactionAmount(t, opts)
 			if err != nil {
-				return nil, fmt.Errorf("error getting transaction amount: %s", err)
+				return nil, fmt.Errorf("error getting transaction amount: %w", err)
 			}
-			total = total.Add(*amount)
+			converted, err := opts.Convert(*amount, processedAt)
+			if err != nil {
+				return nil, fmt.Errorf("error converting transaction amount: %w", err)
+			}
+			total = total.Add(converted)
 		}
 	}
 
 	return &total, nil
 }
 
-func CalcOrderTurnover(o *model.Order, from, to time.Time) (decimal.Decimal, error) {
+// CalcOrderSales returns the order sales recognised in the period: payments processed in it on the cash basis,
+// or all the payments of an order placed in it on the accrual basis.
+func CalcOrderSales(o *model.Order, from, to time.Time, opts CalcOptions) (*decimal.Decimal, error) {
+	if opts.Basis != BasisAccrual {
+		return SumTransactions(o.Transactions, model.OrderTransactionKindSale, from, to, opts)
+	}
+
+	created, err := IsOrderCreatedInPeriod(o, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return &decimal.Zero, nil
+	}
+	return SumTransactions(o.Transactions, model.OrderTransactionKindSale, time.Time{}, maxTime, opts)
+}
+
+// CalcOrderRefunds returns the order refunds processed in the period. A refund is recognised when it's made on either basis.
+func CalcOrderRefunds(o *model.Order, from, to time.Time, opts CalcOptions) (*decimal.Decimal, error) {
+	return SumTransactions(o.Transactions, model.OrderTransactionKindRefund, from, to, opts)
+}
+
+func CalcOrderTurnover(o *model.Order, from, to time.Time, opts CalcOptions) (decimal.Decimal, error) {
 	var income decimal.Decimal
 
-	revenue, err := SumTransactions(o.Transactions, model.OrderTransactionKindSale, from, to)
+	revenue, err := CalcOrderSales(o, from, to, opts)
 	if err != nil {
-		return decimal.Zero, fmt.Errorf("error getting sales total: %s", err)
+		return decimal.Zero, fmt.Errorf("error getting sales total: %w", err)
 	}
 	income = income.Add(*revenue)
 
-	refunds, err := SumTransactions(o.Transactions, model.OrderTransactionKindRefund, from, to)
+	refunds, err := CalcOrderRefunds(o, from, to, opts)
 	if err != nil {
-		return decimal.Zero, fmt.Errorf("error getting refund total: %s", err)
+		return decimal.Zero, fmt.Errorf("error getting refund total: %w", err)
 	}
 	income = income.Sub(*refunds)
 
 	return income, nil
 }
 
-func CalcTotalTurnover(orders []*model.Order, from, to time.Time) (*decimal.Decimal, error) {
+func CalcTotalTurnover(orders []*model.Order, from, to time.Time, opts CalcOptions) (*decimal.Decimal, error) {
 	var total decimal.Decimal
 
 	for _, o := range orders {
-		income, err := CalcOrderTurnover(o, from, to)
+		income, err := CalcOrderTurnover(o, from, to, opts)
 		if err != nil {
-			return nil, fmt.Errorf("income calc: %s", err)
+			return nil, fmt.Errorf("income calc: %w", err)
 		}
 		total = total.Add(income)
 	}
@@ -207,53 +419,74 @@ func CalcTotalTurnover(orders []*model.Order, from, to time.Time) (*decimal.Deci
 	return &total, nil
 }
 
-func CalcOrderNetIncome(o *model.Order, from, to time.Time) (*decimal.Decimal, error) {
-	income, err := CalcOrderTurnover(o, from, to)
+func CalcOrderNetIncome(o *model.Order, from, to time.Time, opts CalcOptions) (*decimal.Decimal, error) {
+	income, err := CalcOrderTurnover(o, from, to, opts)
 	if err != nil {
-		return nil, fmt.Errorf("income calc: %s", err)
+		return nil, fmt.Errorf("income calc: %w", err)
 	}
 
 	if income.IsZero() {
 		return &decimal.Zero, nil
 	}
 
-	if o.ShippingAddress != nil && o.ShippingAddress.CountryCodeV2 != nil && *o.ShippingAddress.CountryCodeV2 == model.CountryCodeGb {
-		for _, t := range o.TaxLines {
-			if t.Rate == nil {
-				continue
-			}
+	if code := GetOrderCountryCode(o); code != nil && *code == model.CountryCodeGb {
+		income = removeOrderTax(o, income)
+	}
 
-			invertRate := decimal.NewFromFloat(1 + *t.Rate)
-			tax := income.Sub(income.Div(invertRate).Round(2))
+	return &income, nil
+}
 
-			income = income.Sub(tax)
-		}
+// CalcOrderTurnoverExTax returns the order turnover for the period without the tax of the order tax lines,
+// whatever the destination, unlike CalcOrderNetIncome which only removes UK VAT.
+func CalcOrderTurnoverExTax(o *model.Order, from, to time.Time, opts CalcOptions) (*decimal.Decimal, error) {
+	income, err := CalcOrderTurnover(o, from, to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("income calc: %w", err)
 	}
 
+	income = removeOrderTax(o, income)
 	return &income, nil
 }
 
-func GetOrderSaleTaxTotal(o *model.Order) (*decimal.Decimal, error) {
-	res := decimal.Zero
-
-	if o.CurrentTotalTaxSet != nil && o.CurrentTotalTaxSet.ShopMoney != nil && !o.CurrentTotalTaxSet.ShopMoney.Amount.IsZero() {
-		tax, err := decimal.NewFromString(o.CurrentTotalTaxSet.ShopMoney.Amount.ValueOrZero())
-		if err != nil {
-			return nil, fmt.Errorf("error parsing total tax set: %s", err)
+// removeOrderTax returns the amount without the tax included in it at each of the order tax line rates.
+func removeOrderTax(o *model.Order, amount decimal.Decimal) decimal.Decimal {
+	for _, t := range o.TaxLines {
+		if t.Rate == nil {
+			continue
 		}
-		res = res.Add(tax)
+
+		invertRate := decimal.NewFromFloat(1 + *t.Rate)
+		tax := amount.Sub(amount.Div(invertRate).Round(2))
+
+		amount = amount.Sub(tax)
+	}
+	return amount
+}
+
+// CalcOrderSaleTax returns the VAT included in the order turnover for the period,
+// i.e. the tax apportioned to the payments and refunds recognised in it.
+func CalcOrderSaleTax(o *model.Order, from, to time.Time, opts CalcOptions) (*decimal.Decimal, error) {
+	income, err := CalcOrderTurnover(o, from, to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("income calc: %w", err)
 	}
 
-	return &res, nil
+	netIncome, err := CalcOrderNetIncome(o, from, to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("net income calc: %w", err)
+	}
+
+	tax := income.Sub(*netIncome)
+	return &tax, nil
 }
 
-func CalcTotalNetTurnover(orders []*model.Order, from, to time.Time) (*decimal.Decimal, error) {
+func CalcTotalNetTurnover(orders []*model.Order, from, to time.Time, opts CalcOptions) (*decimal.Decimal, error) {
 	var total decimal.Decimal
 
 	for _, o := range orders {
-		income, err := CalcOrderNetIncome(o, from, to)
+		income, err := CalcOrderNetIncome(o, from, to, opts)
 		if err != nil {
-			return nil, fmt.Errorf("calculating order net income: %s", err)
+			return nil, fmt.Errorf("calculating order net income: %w", err)
 		}
 
 		total = total.Add(*income)
@@ -262,21 +495,13 @@ func CalcTotalNetTurnover(orders []*model.Order, from, to time.Time) (*decimal.D
 	return &total, nil
 }
 
-func CalcTotalSaleTax(orders []*model.Order, from, to time.Time) (*decimal.Decimal, error) {
+func CalcTotalSaleTax(orders []*model.Order, from, to time.Time, opts CalcOptions) (*decimal.Decimal, error) {
 	var tax decimal.Decimal
 
 	for _, o := range orders {
-		createdAt, err := time.Parse(ISO8601Layout, o.CreatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing created at time: %s", err)
-		}
-		if (createdAt.Before(from) || createdAt.After(to)) && !createdAt.Equal(from) && !createdAt.Equal(to) {
-			continue
-		}
-
-		orderTax, err := GetOrderSaleTaxTotal(o)
+		orderTax, err := CalcOrderSaleTax(o, from, to, opts)
 		if err != nil {
-			return nil, fmt.Errorf("calculating order tax total: %s", err)
+			return nil, fmt.Errorf("calculating order tax total: %w", err)
 		}
 
 		tax = tax.Add(*orderTax)
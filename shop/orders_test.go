@@ -1,11 +1,16 @@
 package shop
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop/shoptest"
 	"github.com/r0busta/go-shopify-reports/utils"
 	"github.com/shopspring/decimal"
 	"gopkg.in/guregu/null.v4"
@@ -19,14 +24,6 @@ func newTime(v time.Time) *time.Time {
 	return &v
 }
 
-func newCountryCode(v model.CountryCode) *model.CountryCode {
-	return &v
-}
-
-func newFloat64(v float64) *float64 {
-	return &v
-}
-
 func TestCalcTotalTurnover(t *testing.T) {
 	from, to, err := utils.ParsePeriod([]string{"2020-04-01", "2020-04-01"})
 	if err != nil {
@@ -133,7 +130,7 @@ func TestCalcTotalTurnover(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, gotErr := CalcTotalTurnover(tt.args.orders, tt.args.from, tt.args.to)
+			got, gotErr := CalcTotalTurnover(tt.args.orders, tt.args.from, tt.args.to, CalcOptions{})
 			if gotErr != nil {
 				t.Errorf("CalcTotalTurnover(), gotErr=%v, want %v", gotErr.Error(), nil)
 			}
@@ -175,7 +172,7 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 				orders: []*model.Order{
 					{
 						ShippingAddress: &model.MailingAddress{
-							CountryCodeV2: newCountryCode(model.CountryCodeGb),
+							CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeGb),
 						},
 						Transactions: []model.OrderTransaction{
 							{
@@ -202,7 +199,7 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 				orders: []*model.Order{
 					{
 						ShippingAddress: &model.MailingAddress{
-							CountryCodeV2: newCountryCode(model.CountryCodeGb),
+							CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeGb),
 						},
 						Transactions: []model.OrderTransaction{
 							{
@@ -234,7 +231,7 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 				orders: []*model.Order{
 					{
 						ShippingAddress: &model.MailingAddress{
-							CountryCodeV2: newCountryCode(model.CountryCodeGb),
+							CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeGb),
 						},
 						Transactions: []model.OrderTransaction{
 							{
@@ -251,7 +248,7 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 						},
 						TaxLines: []model.TaxLine{
 							{
-								Rate: newFloat64(0.2),
+								Rate: shoptest.NewFloat64(0.2),
 							},
 						},
 					},
@@ -266,7 +263,7 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 				orders: []*model.Order{
 					{
 						ShippingAddress: &model.MailingAddress{
-							CountryCodeV2: newCountryCode(model.CountryCodeGb),
+							CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeGb),
 						},
 						Transactions: []model.OrderTransaction{
 							{
@@ -294,7 +291,7 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 						},
 						TaxLines: []model.TaxLine{
 							{
-								Rate: newFloat64(0.2),
+								Rate: shoptest.NewFloat64(0.2),
 							},
 						},
 					},
@@ -309,7 +306,7 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 				orders: []*model.Order{
 					{
 						ShippingAddress: &model.MailingAddress{
-							CountryCodeV2: newCountryCode(model.CountryCodeGb),
+							CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeGb),
 						},
 						Transactions: []model.OrderTransaction{
 							{
@@ -337,7 +334,7 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 						},
 						TaxLines: []model.TaxLine{
 							{
-								Rate: newFloat64(0.2),
+								Rate: shoptest.NewFloat64(0.2),
 							},
 						},
 					},
@@ -352,7 +349,7 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 				orders: []*model.Order{
 					{
 						ShippingAddress: &model.MailingAddress{
-							CountryCodeV2: newCountryCode(model.CountryCodeGb),
+							CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeGb),
 						},
 						Transactions: []model.OrderTransaction{
 							{
@@ -380,13 +377,13 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 						},
 						TaxLines: []model.TaxLine{
 							{
-								Rate: newFloat64(0.2),
+								Rate: shoptest.NewFloat64(0.2),
 							},
 						},
 					},
 					{
 						ShippingAddress: &model.MailingAddress{
-							CountryCodeV2: newCountryCode(model.CountryCodeGb),
+							CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeGb),
 						},
 						Transactions: []model.OrderTransaction{
 							{
@@ -403,7 +400,7 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 						},
 						TaxLines: []model.TaxLine{
 							{
-								Rate: newFloat64(0.2),
+								Rate: shoptest.NewFloat64(0.2),
 							},
 						},
 					},
@@ -418,7 +415,7 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 				orders: []*model.Order{
 					{
 						ShippingAddress: &model.MailingAddress{
-							CountryCodeV2: newCountryCode(model.CountryCodeGb),
+							CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeGb),
 						},
 						Transactions: []model.OrderTransaction{
 							{
@@ -435,13 +432,13 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 						},
 						TaxLines: []model.TaxLine{
 							{
-								Rate: newFloat64(0.2),
+								Rate: shoptest.NewFloat64(0.2),
 							},
 						},
 					},
 					{
 						ShippingAddress: &model.MailingAddress{
-							CountryCodeV2: newCountryCode(model.CountryCodeGb),
+							CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeGb),
 						},
 						Transactions: []model.OrderTransaction{
 							{
@@ -458,7 +455,7 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 						},
 						TaxLines: []model.TaxLine{
 							{
-								Rate: newFloat64(0.2),
+								Rate: shoptest.NewFloat64(0.2),
 							},
 						},
 					},
@@ -469,7 +466,7 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, gotErr := CalcTotalNetTurnover(tt.args.orders, tt.args.from, tt.args.to)
+			got, gotErr := CalcTotalNetTurnover(tt.args.orders, tt.args.from, tt.args.to, CalcOptions{})
 			if gotErr != nil {
 				t.Errorf("CalcTotalNetTurnover(), gotErr=%v, want %v", gotErr.Error(), nil)
 			}
@@ -479,3 +476,182 @@ func TestCalcTotalNetTurnover(t *testing.T) {
 		})
 	}
 }
+
+func TestCalcOrderTurnoverBasis(t *testing.T) {
+	from, to, err := utils.ParsePeriod([]string{"2020-04-01", "2020-04-30"})
+	if err != nil {
+		log.Fatalf("error parsing period: %s", err)
+	}
+
+	newTransaction := func(kind model.OrderTransactionKind, amount string, processedAt time.Time) model.OrderTransaction {
+		return model.OrderTransaction{
+			ProcessedAt: model.NewString(processedAt.Format(ISO8601Layout)),
+			Kind:        kind,
+			Status:      model.OrderTransactionStatusSuccess,
+			AmountSet: &model.MoneyBag{
+				ShopMoney: &model.MoneyV2{
+					Amount: null.StringFrom(amount),
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		order   *model.Order
+		cash    *decimal.Decimal
+		accrual *decimal.Decimal
+	}{
+		{
+			name: "Ordered before the period and paid in it",
+			order: &model.Order{
+				CreatedAt: time.Date(2020, 3, 31, 23, 0, 0, 0, time.UTC).Format(ISO8601Layout),
+				Transactions: []model.OrderTransaction{
+					newTransaction(model.OrderTransactionKindSale, "10.00", time.Date(2020, 4, 1, 9, 0, 0, 0, time.UTC)),
+				},
+			},
+			cash:    newDecimal(decimal.RequireFromString("10.00")),
+			accrual: newDecimal(decimal.Zero),
+		},
+		{
+			name: "Ordered in the period and paid after it",
+			order: &model.Order{
+				CreatedAt: time.Date(2020, 4, 30, 23, 0, 0, 0, time.UTC).Format(ISO8601Layout),
+				Transactions: []model.OrderTransaction{
+					newTransaction(model.OrderTransactionKindSale, "10.00", time.Date(2020, 5, 1, 9, 0, 0, 0, time.UTC)),
+				},
+			},
+			cash:    newDecimal(decimal.Zero),
+			accrual: newDecimal(decimal.RequireFromString("10.00")),
+		},
+		{
+			name: "Ordered before the period and refunded in it",
+			order: &model.Order{
+				CreatedAt: time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC).Format(ISO8601Layout),
+				Transactions: []model.OrderTransaction{
+					newTransaction(model.OrderTransactionKindSale, "10.00", time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)),
+					newTransaction(model.OrderTransactionKindRefund, "4.00", time.Date(2020, 4, 2, 10, 0, 0, 0, time.UTC)),
+				},
+			},
+			cash:    newDecimal(decimal.RequireFromString("-4.00")),
+			accrual: newDecimal(decimal.RequireFromString("-4.00")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := CalcOrderTurnover(tt.order, *from, *to, CalcOptions{Basis: BasisCash})
+			if gotErr != nil {
+				t.Errorf("CalcOrderTurnover(), gotErr=%v, want %v", gotErr.Error(), nil)
+			}
+			if !got.Equal(*tt.cash) {
+				t.Errorf("CalcOrderTurnover() on cash basis = %v, want %v", got.String(), tt.cash.String())
+			}
+
+			got, gotErr = CalcOrderTurnover(tt.order, *from, *to, CalcOptions{Basis: BasisAccrual})
+			if gotErr != nil {
+				t.Errorf("CalcOrderTurnover(), gotErr=%v, want %v", gotErr.Error(), nil)
+			}
+			if !got.Equal(*tt.accrual) {
+				t.Errorf("CalcOrderTurnover() on accrual basis = %v, want %v", got.String(), tt.accrual.String())
+			}
+		})
+	}
+}
+
+// logLines records the messages logged.
+type logLines []string
+
+func (l *logLines) Printf(format string, args ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, args...))
+}
+
+func TestOrderServiceOp(t *testing.T) {
+	srv, err := shoptest.NewServer(shoptest.Orders)
+	if err != nil {
+		t.Fatalf("NewServer(), err=%v", err)
+	}
+	defer srv.Close()
+	srv.Polls = 1
+
+	t.Setenv("STORE_API_URL", srv.GraphQLURL())
+	c := NewClient()
+	c.cache = NewOrderCache(t.TempDir(), "test")
+	s := &OrderServiceOp{client: c, cache: c.cache}
+
+	logged := &logLines{}
+	Log = logged
+	defer func() { Log = nopLogger{} }()
+
+	names := func(orders []*model.Order) []string {
+		res := []string{}
+		for _, o := range orders {
+			res = append(res, o.Name)
+		}
+		return res
+	}
+
+	from := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 4, 30, 23, 59, 59, 0, time.UTC)
+	orders, err := s.ListCreatedBetween(context.Background(), from, to, false)
+	if err != nil {
+		t.Fatalf("ListCreatedBetween(), err=%v", err)
+	}
+	if got, want := names(orders), []string{"#1002", "#1003", "#1004", "#1005", "#1006"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListCreatedBetween() = %v, want %v", got, want)
+	}
+	if got := len(orders[0].LineItems.Edges); got != 2 {
+		t.Errorf("ListCreatedBetween() order #1002 has %d line items, want 2", got)
+	}
+	if r := orders[1].Refunds; len(r) != 1 || r[0].RefundLineItems == nil || len(r[0].RefundLineItems.Edges) != 1 ||
+		r[0].RefundLineItems.Edges[0].Node.LineItem.ID != "gid://shopify/LineItem/300" {
+		t.Errorf("ListCreatedBetween() order #1003 refunds = %+v, want one refunding line item 300", r)
+	}
+	if got, want := srv.Filters()[0], "created_at:>='2020-04-01T00:00:00Z' created_at:<='2020-04-30T23:59:59Z'"; !strings.Contains(got, want) {
+		t.Errorf("bulk query filter = %q, want it to contain %q", got, want)
+	}
+
+	_, err = s.ListCreatedBetween(context.Background(), from, to, true)
+	if err != nil {
+		t.Fatalf("ListCreatedBetween() cached, err=%v", err)
+	}
+	if got := len(srv.Filters()); got != 1 {
+		t.Errorf("ListCreatedBetween() cached ran %d bulk queries, want 1", got)
+	}
+	if got := strings.Join(*logged, "\n"); !strings.Contains(got, "Using orders cached on") {
+		t.Errorf("ListCreatedBetween() cached logged %q, want it to say the cached orders are used", got)
+	}
+
+	orders, err = s.ListUpdatedSince(context.Background(), newTime(time.Date(2020, 4, 10, 0, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatalf("ListUpdatedSince(), err=%v", err)
+	}
+	if got, want := names(orders), []string{"#1003", "#1004", "#1005"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListUpdatedSince() = %v, want %v", got, want)
+	}
+
+	orders, err = s.ListCreatedBetween(context.Background(), from.AddDate(1, 0, 0), to.AddDate(1, 0, 0), false)
+	if err != nil {
+		t.Fatalf("ListCreatedBetween() without orders, err=%v", err)
+	}
+	if len(orders) != 0 {
+		t.Errorf("ListCreatedBetween() without orders = %v, want none", names(orders))
+	}
+
+	tz, err := c.Timezone(context.Background())
+	if err != nil {
+		t.Fatalf("Timezone(), err=%v", err)
+	}
+	if tz != "Europe/London" {
+		t.Errorf("Timezone() = %s, want Europe/London", tz)
+	}
+
+	// The timezone is saved with the cached orders and not queried again
+	srv.Timezone = "America/New_York"
+	tz, err = c.Timezone(context.Background())
+	if err != nil {
+		t.Fatalf("Timezone() cached, err=%v", err)
+	}
+	if tz != "Europe/London" {
+		t.Errorf("Timezone() cached = %s, want Europe/London", tz)
+	}
+}
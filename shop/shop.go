@@ -0,0 +1,100 @@
+package shop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/thoas/go-funk"
+)
+
+const shopTimezoneQuery = `
+	{
+		shop {
+			ianaTimezone
+		}
+	}
+	`
+
+// Timezone returns the shop IANA timezone name: the store profile's, or the one saved by the last sync offline
+// or with the cached orders online. Otherwise it's read from Shopify and saved with the cached orders.
+// The stores of a consolidated client must be in the same timezone.
+func (c *Client) Timezone(ctx context.Context) (string, error) {
+	if c.timezone != "" {
+		return c.timezone, nil
+	}
+	if len(c.stores) > 0 {
+		return c.storesTimezone(ctx)
+	}
+	if c.shopifyClient == nil {
+		tz, err := c.db.Timezone()
+		if err != nil {
+			return "", fmt.Errorf("error reading shop timezone: %w", err)
+		}
+		return tz, nil
+	}
+
+	tz, err := c.cache.Timezone()
+	if err != nil {
+		return "", fmt.Errorf("error reading shop timezone: %w", err)
+	}
+	if tz != "" {
+		return tz, nil
+	}
+
+	var out struct {
+		Shop model.Shop `json:"shop"`
+	}
+	err = c.shopifyClient.GraphQLClient().QueryString(ctx, shopTimezoneQuery, nil, &out)
+	if err != nil {
+		return "", fmt.Errorf("error querying shop timezone: %w", err)
+	}
+	err = c.cache.SaveTimezone(out.Shop.IanaTimezone)
+	if err != nil {
+		return "", fmt.Errorf("error caching shop timezone: %w", err)
+	}
+	return out.Shop.IanaTimezone, nil
+}
+
+func (c *Client) storesTimezone(ctx context.Context) (string, error) {
+	res := ""
+	timezones := []string{}
+	for _, sc := range c.stores {
+		tz, err := sc.Timezone(ctx)
+		if err != nil {
+			return "", fmt.Errorf("store %s: %w", sc.name, err)
+		}
+		if !funk.ContainsString(timezones, tz) {
+			timezones = append(timezones, tz)
+		}
+		res = tz
+	}
+	if len(timezones) > 1 {
+		return "", fmt.Errorf("stores are in different timezones (%s), set the report timezone", strings.Join(timezones, ", "))
+	}
+	return res, nil
+}
+
+// Location returns the timezone periods are in: the named one, or the shop's if the name is empty.
+// It falls back to UTC when the shop timezone isn't known.
+func (c *Client) Location(ctx context.Context, name string) (*time.Location, error) {
+	if name == "" {
+		tz, err := c.Timezone(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if tz == "" {
+			Log.Printf("Shop timezone unknown, using UTC")
+			return time.UTC, nil
+		}
+		name = tz
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("error loading timezone: %w", err)
+	}
+	return loc, nil
+}
@@ -0,0 +1,51 @@
+package shoptest
+
+import (
+	"context"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+)
+
+// OrderService serves a fixed list of orders as a shop.OrderService, or the context error once it's done.
+// It doesn't import shop, so the shop package's own tests can use it too.
+type OrderService struct {
+	Orders []*model.Order
+	// Since are the times ListUpdatedSince was called with.
+	Since []*time.Time
+}
+
+// ListCreatedBetween returns the orders created, updated or processed in the range, as the bulk query filter does.
+func (s *OrderService) ListCreatedBetween(ctx context.Context, from, to time.Time, useCached bool) ([]*model.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	res := []*model.Order{}
+	for _, o := range s.Orders {
+		for _, ts := range []string{o.CreatedAt, o.UpdatedAt, o.ProcessedAt} {
+			t, err := time.Parse(time.RFC3339, ts)
+			if err == nil && !t.Before(from) && !t.After(to) {
+				res = append(res, o)
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// ListUpdatedSince returns all the orders, whatever the time.
+func (s *OrderService) ListUpdatedSince(ctx context.Context, since *time.Time) ([]*model.Order, error) {
+	s.Since = append(s.Since, since)
+	return s.Orders, ctx.Err()
+}
+
+// NewCountryCode returns a pointer to the country code, for the optional fields of orders.
+func NewCountryCode(v model.CountryCode) *model.CountryCode {
+	return &v
+}
+
+// NewFloat64 returns a pointer to the number, e.g. for tax line rates.
+func NewFloat64(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,402 @@
+// Package shoptest provides a fake Shopify Admin GraphQL API serving orders from a bulk operation JSONL fixture,
+// for testing the order queries and the commands end-to-end offline, and a fake order service for the reports.
+package shoptest
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const GraphQLPath = "/admin/api/2022-04/graphql.json"
+
+// Orders is a fixture of GBP orders in March and April 2020: UK sales at 20% VAT, a sale to Germany,
+// a refund in April of a March order, an order refunded in full and one paid by a test transaction.
+// Order lines carry their refunds as the nodes query returns them.
+//
+//go:embed testdata/orders.jsonl
+var Orders []byte
+
+var (
+	filterRegex    = regexp.MustCompile(`(?s)orders\(query:\s*"(.*?)"\)`)
+	groupRegex     = regexp.MustCompile(`\(([^()]*)\)`)
+	conditionRegex = regexp.MustCompile(`(\w+):(>=|<=|>|<)'([^']*)'`)
+
+	filterFields = map[string]string{
+		"created_at":   "createdAt",
+		"updated_at":   "updatedAt",
+		"processed_at": "processedAt",
+	}
+)
+
+// Server is a fake store. Orders are the lines of a bulk query result without a parent,
+// the other lines (e.g. line items) are returned with the order they belong to.
+type Server struct {
+	*httptest.Server
+
+	// Timezone is the shop IANA timezone
+	Timezone string
+	// Polls is the number of times a bulk operation is reported running before it completes
+	Polls int
+
+	mu         sync.Mutex
+	orders     []json.RawMessage
+	children   map[string][]json.RawMessage
+	operations []*operation
+	filters    []string
+}
+
+type operation struct {
+	id      string
+	status  string
+	polls   int
+	results []byte
+	count   int
+}
+
+// NewServer starts a fake store with the orders of the bulk query result fixture.
+func NewServer(fixture []byte) (*Server, error) {
+	s := &Server{
+		Timezone: "Europe/London",
+		children: map[string][]json.RawMessage{},
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(fixture))
+	sc.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var node struct {
+			ParentID string `json:"__parentId"`
+		}
+		if err := json.Unmarshal(line, &node); err != nil {
+			return nil, fmt.Errorf("error parsing fixture line %d: %w", n, err)
+		}
+		raw := json.RawMessage(append([]byte{}, line...))
+		if node.ParentID != "" {
+			s.children[node.ParentID] = append(s.children[node.ParentID], raw)
+		} else {
+			s.orders = append(s.orders, raw)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("error reading fixture: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(GraphQLPath, s.handleGraphQL)
+	mux.HandleFunc("/results/", s.handleResults)
+
+	s.Server = httptest.NewServer(mux)
+	return s, nil
+}
+
+// GraphQLURL is the endpoint to set STORE_API_URL to.
+func (s *Server) GraphQLURL() string {
+	return s.URL + GraphQLPath
+}
+
+// Filters returns the order search queries of the bulk operations run so far.
+func (s *Server) Filters() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.filters...)
+}
+
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrors(w, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case strings.Contains(req.Query, "bulkOperationRunQuery"):
+		query, _ := req.Variables["query"].(string)
+		s.runQuery(w, query)
+	case strings.Contains(req.Query, "bulkOperationCancel"):
+		s.cancel(w)
+	case strings.Contains(req.Query, "currentBulkOperation"):
+		writeData(w, map[string]interface{}{"currentBulkOperation": s.current()})
+	case strings.Contains(req.Query, "nodes(ids:"):
+		ids, _ := req.Variables["ids"].([]interface{})
+		s.refunds(w, ids)
+	case strings.Contains(req.Query, "shop"):
+		writeData(w, map[string]interface{}{"shop": map[string]string{"ianaTimezone": s.Timezone}})
+	default:
+		writeErrors(w, fmt.Sprintf("unsupported query: %s", req.Query))
+	}
+}
+
+func (s *Server) runQuery(w http.ResponseWriter, query string) {
+	if op := s.last(); op != nil && (op.status == "CREATED" || op.status == "RUNNING") {
+		writeData(w, map[string]interface{}{"bulkOperationRunQuery": map[string]interface{}{
+			"bulkOperation": nil,
+			"userErrors": []map[string]interface{}{{
+				"field":   []string{"query"},
+				"message": fmt.Sprintf("A bulk query operation for this app and shop is already in progress: %s.", op.id),
+			}},
+		}})
+		return
+	}
+
+	m := filterRegex.FindStringSubmatch(query)
+	if m == nil {
+		writeData(w, map[string]interface{}{"bulkOperationRunQuery": map[string]interface{}{
+			"bulkOperation": nil,
+			"userErrors":    []map[string]interface{}{{"field": []string{"query"}, "message": "Only order queries are supported"}},
+		}})
+		return
+	}
+	filter := strings.TrimSpace(m[1])
+	s.filters = append(s.filters, filter)
+
+	results, count, err := s.results(filter)
+	if err != nil {
+		writeErrors(w, err.Error())
+		return
+	}
+	op := &operation{
+		id:      fmt.Sprintf("gid://shopify/BulkOperation/%d", len(s.operations)+1),
+		status:  "CREATED",
+		polls:   s.Polls,
+		results: results,
+		count:   count,
+	}
+	s.operations = append(s.operations, op)
+
+	writeData(w, map[string]interface{}{"bulkOperationRunQuery": map[string]interface{}{
+		"bulkOperation": map[string]string{"id": op.id, "status": op.status},
+		"userErrors":    []interface{}{},
+	}})
+}
+
+func (s *Server) cancel(w http.ResponseWriter) {
+	op := s.last()
+	if op != nil && (op.status == "CREATED" || op.status == "RUNNING") {
+		op.status = "CANCELED"
+	}
+	writeData(w, map[string]interface{}{"bulkOperationCancel": map[string]interface{}{
+		"bulkOperation": s.current(),
+		"userErrors":    []interface{}{},
+	}})
+}
+
+// current reports the last bulk operation, advancing it one poll towards completion.
+func (s *Server) current() interface{} {
+	op := s.last()
+	if op == nil {
+		return nil
+	}
+
+	switch op.status {
+	case "CREATED", "RUNNING":
+		if op.polls > 0 {
+			op.polls--
+			op.status = "RUNNING"
+		} else {
+			op.status = "COMPLETED"
+		}
+	}
+
+	res := map[string]interface{}{
+		"id":          op.id,
+		"status":      op.status,
+		"errorCode":   nil,
+		"objectCount": "0",
+		"url":         nil,
+	}
+	if op.status == "COMPLETED" {
+		res["objectCount"] = fmt.Sprint(op.count)
+		if op.count > 0 {
+			res["url"] = fmt.Sprintf("%s/results/%d.jsonl", s.URL, len(s.operations))
+		}
+	}
+	return res
+}
+
+func (s *Server) last() *operation {
+	if len(s.operations) == 0 {
+		return nil
+	}
+	return s.operations[len(s.operations)-1]
+}
+
+func (s *Server) handleResults(w http.ResponseWriter, r *http.Request) {
+	var n int
+	if _, err := fmt.Sscanf(r.URL.Path, "/results/%d.jsonl", &n); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n < 1 || n > len(s.operations) || s.operations[n-1].status != "COMPLETED" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jsonl")
+	w.Write(s.operations[n-1].results)
+}
+
+// refunds serves the refunds of the orders, in the order of the IDs and null for the unknown ones.
+func (s *Server) refunds(w http.ResponseWriter, ids []interface{}) {
+	nodes := []interface{}{}
+	for _, id := range ids {
+		var node interface{}
+		for _, o := range s.orders {
+			var fields map[string]interface{}
+			if err := json.Unmarshal(o, &fields); err != nil {
+				writeErrors(w, err.Error())
+				return
+			}
+			if fields["id"] == id {
+				node = map[string]interface{}{"id": id, "refunds": fields["refunds"]}
+				break
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	writeData(w, map[string]interface{}{"nodes": nodes})
+}
+
+// results returns the JSONL lines of the orders matching the filter, each followed by its children, and their count.
+func (s *Server) results(filter string) ([]byte, int, error) {
+	match, err := parseFilter(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var buf bytes.Buffer
+	count := 0
+	for _, o := range s.orders {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(o, &fields); err != nil {
+			return nil, 0, err
+		}
+		if !match(fields) {
+			continue
+		}
+
+		// Bulk queries can't select the refunded lines, the refunds are served by the nodes query instead
+		if _, ok := fields["refunds"]; ok {
+			delete(fields, "refunds")
+			o, err = json.Marshal(fields)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+
+		id, _ := fields["id"].(string)
+		for _, line := range append([]json.RawMessage{o}, s.children[id]...) {
+			buf.Write(line)
+			buf.WriteByte('\n')
+			count++
+		}
+	}
+	return buf.Bytes(), count, nil
+}
+
+// parseFilter parses the order search query of the bulk operations: conditions on the created, updated
+// or processed dates, all of them in a group and any of the groups, e.g. (a AND b) OR (c AND d).
+// An empty filter matches all orders.
+func parseFilter(filter string) (func(fields map[string]interface{}) bool, error) {
+	groups := []string{filter}
+	if m := groupRegex.FindAllStringSubmatch(filter, -1); m != nil {
+		groups = []string{}
+		for _, g := range m {
+			groups = append(groups, g[1])
+		}
+	}
+
+	type condition struct {
+		field string
+		op    string
+		value time.Time
+	}
+	parsed := [][]condition{}
+	for _, g := range groups {
+		conds := []condition{}
+		for _, c := range conditionRegex.FindAllStringSubmatch(g, -1) {
+			field, ok := filterFields[c[1]]
+			if !ok {
+				return nil, fmt.Errorf("unsupported filter field `%s`", c[1])
+			}
+			t, err := time.Parse(time.RFC3339, c[3])
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter date `%s`", c[3])
+			}
+			conds = append(conds, condition{field, c[2], t})
+		}
+		if len(conds) == 0 && strings.TrimSpace(g) != "" {
+			return nil, fmt.Errorf("unsupported filter `%s`", g)
+		}
+		parsed = append(parsed, conds)
+	}
+
+	return func(fields map[string]interface{}) bool {
+		for _, conds := range parsed {
+			ok := true
+			for _, c := range conds {
+				v, _ := fields[c.field].(string)
+				t, err := time.Parse(time.RFC3339, v)
+				if err != nil {
+					ok = false
+					break
+				}
+				switch c.op {
+				case ">=":
+					ok = !t.Before(c.value)
+				case "<=":
+					ok = !t.After(c.value)
+				case ">":
+					ok = t.After(c.value)
+				case "<":
+					ok = t.Before(c.value)
+				}
+				if !ok {
+					break
+				}
+			}
+			if ok {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func writeData(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func writeErrors(w http.ResponseWriter, messages ...string) {
+	errs := []map[string]string{}
+	for _, m := range messages {
+		errs = append(errs, map[string]string{"message": m})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"errors": errs})
+}
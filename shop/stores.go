@@ -0,0 +1,172 @@
+package shop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+)
+
+// AllStores selects every store of the stores file, for consolidated reports.
+const AllStores = "all"
+
+// Store is a named store profile of the stores file.
+type Store struct {
+	Name string `json:"name"`
+	// Domain is the shop's myshopify.com domain, e.g. acme-uk.myshopify.com
+	Domain string `json:"domain"`
+	// Token is the Admin API access token
+	Token string `json:"token"`
+	// Currency is the default report currency
+	Currency string `json:"currency,omitempty"`
+	// Timezone is the IANA timezone period dates are in, instead of the shop's
+	Timezone  string `json:"timezone,omitempty"`
+	VATNumber string `json:"vatNumber,omitempty"`
+	// APIURL is a GraphQL endpoint to use instead of the shop's Admin API, e.g. a fake store (see shoptest)
+	APIURL string `json:"apiUrl,omitempty"`
+}
+
+// LoadStores reads the store profiles of a JSON stores file, e.g.
+//
+//	{"stores": [{"name": "uk", "domain": "acme-uk.myshopify.com", "token": "shpat_...", "currency": "GBP", "timezone": "Europe/London", "vatNumber": "123456789"}]}
+func LoadStores(path string) ([]Store, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading stores file: %w", err)
+	}
+	var file struct {
+		Stores []Store `json:"stores"`
+	}
+	err = json.Unmarshal(b, &file)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing stores file %s: %w", path, err)
+	}
+
+	seen := map[string]bool{}
+	for i, s := range file.Stores {
+		switch {
+		case s.Name == "":
+			return nil, fmt.Errorf("store %d of %s has no name", i+1, path)
+		case s.Name == AllStores:
+			return nil, fmt.Errorf("store name `%s` is reserved for all the stores", AllStores)
+		case seen[s.Name]:
+			return nil, fmt.Errorf("store `%s` is listed more than once in %s", s.Name, path)
+		case s.APIURL == "" && (s.Domain == "" || s.Token == ""):
+			return nil, fmt.Errorf("store `%s` needs a domain and an access token", s.Name)
+		}
+		seen[s.Name] = true
+	}
+	if len(file.Stores) == 0 {
+		return nil, fmt.Errorf("no stores in %s", path)
+	}
+	return file.Stores, nil
+}
+
+// FindStores returns the named store profile, or all of them for AllStores.
+func FindStores(stores []Store, name string) ([]Store, error) {
+	if name == AllStores {
+		return stores, nil
+	}
+	names := []string{}
+	for _, s := range stores {
+		if s.Name == name {
+			return []Store{s}, nil
+		}
+		names = append(names, s.Name)
+	}
+	return nil, fmt.Errorf("unknown store `%s`, expected one of %s or %s", name, strings.Join(names, ", "), AllStores)
+}
+
+// shopName is the shop's myshopify.com subdomain, which the Admin API endpoint is built from.
+func (s Store) shopName() string {
+	name := strings.TrimPrefix(strings.TrimPrefix(s.Domain, "https://"), "http://")
+	return strings.TrimSuffix(strings.TrimSuffix(name, "/"), ".myshopify.com")
+}
+
+// MultiStoreService lists the orders of several stores as one, for consolidated reports.
+// It remembers which store each order it listed is from.
+type MultiStoreService struct {
+	names    []string
+	services []OrderService
+
+	mu    sync.Mutex
+	store map[*model.Order]string
+}
+
+var _ OrderService = &MultiStoreService{}
+
+func NewMultiStoreService() *MultiStoreService {
+	return &MultiStoreService{
+		store: map[*model.Order]string{},
+	}
+}
+
+// Add adds the orders of a store.
+func (s *MultiStoreService) Add(name string, service OrderService) {
+	s.names = append(s.names, name)
+	s.services = append(s.services, service)
+}
+
+// Stores returns the store names in the order they were added.
+func (s *MultiStoreService) Stores() []string {
+	return append([]string{}, s.names...)
+}
+
+// Store returns the name of the store the order is from, or an empty string if the service didn't list it.
+func (s *MultiStoreService) Store(o *model.Order) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store[o]
+}
+
+// ByStore splits the orders by the store they're from, in the order of Stores.
+func (s *MultiStoreService) ByStore(orders []*model.Order) [][]*model.Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([][]*model.Order, len(s.names))
+	for _, o := range orders {
+		for i, name := range s.names {
+			if s.store[o] == name {
+				res[i] = append(res[i], o)
+				break
+			}
+		}
+	}
+	return res
+}
+
+func (s *MultiStoreService) ListCreatedBetween(ctx context.Context, from, to time.Time, useCached bool) ([]*model.Order, error) {
+	return s.list(func(service OrderService) ([]*model.Order, error) {
+		return service.ListCreatedBetween(ctx, from, to, useCached)
+	})
+}
+
+func (s *MultiStoreService) ListUpdatedSince(ctx context.Context, since *time.Time) ([]*model.Order, error) {
+	return s.list(func(service OrderService) ([]*model.Order, error) {
+		return service.ListUpdatedSince(ctx, since)
+	})
+}
+
+func (s *MultiStoreService) list(list func(service OrderService) ([]*model.Order, error)) ([]*model.Order, error) {
+	res := []*model.Order{}
+	for i, service := range s.services {
+		orders, err := list(service)
+		if err != nil {
+			return nil, fmt.Errorf("store %s: %w", s.names[i], err)
+		}
+
+		s.mu.Lock()
+		for _, o := range orders {
+			s.store[o] = s.names[i]
+		}
+		s.mu.Unlock()
+		res = append(res, orders...)
+	}
+	return res, nil
+}
@@ -0,0 +1,105 @@
+package shop
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop/shoptest"
+)
+
+func TestLoadStores(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", `{"stores": [{"name": "uk", "domain": "acme-uk.myshopify.com", "token": "shpat_1"}, {"name": "eu", "apiUrl": "http://localhost/graphql.json"}]}`, ""},
+		{"no stores", `{"stores": []}`, "no stores"},
+		{"no name", `{"stores": [{"domain": "acme-uk.myshopify.com", "token": "shpat_1"}]}`, "has no name"},
+		{"reserved name", `{"stores": [{"name": "all", "domain": "acme-uk.myshopify.com", "token": "shpat_1"}]}`, "reserved"},
+		{"duplicate", `{"stores": [{"name": "uk", "domain": "a", "token": "1"}, {"name": "uk", "domain": "b", "token": "2"}]}`, "more than once"},
+		{"no token", `{"stores": [{"name": "uk", "domain": "acme-uk.myshopify.com"}]}`, "needs a domain and an access token"},
+		{"invalid", `{"stores": {}}`, "error parsing stores file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "stores.json")
+			err := os.WriteFile(path, []byte(tt.content), 0600)
+			if err != nil {
+				t.Fatalf("WriteFile(), err=%v", err)
+			}
+
+			_, err = LoadStores(path)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("LoadStores(), err=%v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("LoadStores(), err=%v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindStores(t *testing.T) {
+	stores := []Store{{Name: "uk"}, {Name: "eu"}}
+
+	got, err := FindStores(stores, "eu")
+	if err != nil || len(got) != 1 || got[0].Name != "eu" {
+		t.Errorf("FindStores(eu) = %v, err=%v", got, err)
+	}
+	got, err = FindStores(stores, AllStores)
+	if err != nil || len(got) != 2 {
+		t.Errorf("FindStores(all) = %v, err=%v", got, err)
+	}
+	_, err = FindStores(stores, "us")
+	if err == nil || !strings.Contains(err.Error(), "expected one of uk, eu or all") {
+		t.Errorf("FindStores(us), err=%v", err)
+	}
+}
+
+func TestStoreShopName(t *testing.T) {
+	for _, domain := range []string{"acme-uk", "acme-uk.myshopify.com", "https://acme-uk.myshopify.com/"} {
+		if got := (Store{Domain: domain}).shopName(); got != "acme-uk" {
+			t.Errorf("shopName() of %s = %s, want acme-uk", domain, got)
+		}
+	}
+}
+
+func TestMultiStoreService(t *testing.T) {
+	uk := &shoptest.OrderService{Orders: []*model.Order{
+		{ID: "gid://shopify/Order/1", CreatedAt: "2020-04-01T10:00:00Z"},
+		{ID: "gid://shopify/Order/2", CreatedAt: "2020-05-01T10:00:00Z"},
+	}}
+	eu := &shoptest.OrderService{Orders: []*model.Order{
+		{ID: "gid://shopify/Order/3", CreatedAt: "2020-04-02T10:00:00Z"},
+	}}
+	s := NewMultiStoreService()
+	s.Add("uk", uk)
+	s.Add("eu", eu)
+
+	from := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 4, 30, 23, 59, 59, 0, time.UTC)
+	orders, err := s.ListCreatedBetween(context.Background(), from, to, false)
+	if err != nil {
+		t.Fatalf("ListCreatedBetween(), err=%v", err)
+	}
+	if len(orders) != 2 {
+		t.Fatalf("ListCreatedBetween() returned %d orders, want 2", len(orders))
+	}
+	if got := s.Store(orders[1]); got != "eu" {
+		t.Errorf("Store() of order 3 = %q, want eu", got)
+	}
+	if got := s.Store(&model.Order{}); got != "" {
+		t.Errorf("Store() of an order not listed = %q, want empty", got)
+	}
+
+	byStore := s.ByStore(orders)
+	if len(byStore) != 2 || len(byStore[0]) != 1 || len(byStore[1]) != 1 || byStore[1][0].ID != "gid://shopify/Order/3" {
+		t.Errorf("ByStore() = %v", byStore)
+	}
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"time"
+)
+
+const (
+	ComparePrevious = "previous"
+	CompareYearAgo  = "year-ago"
+)
+
+// ParseComparePeriod returns the period to compare the one from and to with: the previous period of the same length,
+// the same period a year ago, or the period given as for ParsePeriod. Periods of whole months are shifted by months.
+func ParseComparePeriod(compare []string, from, to time.Time) (*time.Time, *time.Time, error) {
+	if len(compare) != 1 || compare[0] != ComparePrevious && compare[0] != CompareYearAgo {
+		return ParsePeriodIn(compare, from.Location())
+	}
+
+	var prevFrom, prevTo time.Time
+	switch compare[0] {
+	case ComparePrevious:
+		if months := wholeMonths(from, to); months > 0 {
+			prevFrom = from.AddDate(0, -months, 0)
+		} else {
+			prevFrom = from.AddDate(0, 0, -calendarDays(from, to))
+		}
+		prevTo = from.Add(-time.Nanosecond)
+	case CompareYearAgo:
+		prevFrom = from.AddDate(-1, 0, 0)
+		if wholeMonths(from, to) > 0 {
+			// Keep the end of February a year before or after a leap year
+			prevTo = time.Date(to.Year()-1, to.Month()+1, 1, 0, 0, 0, 0, to.Location()).Add(-time.Nanosecond)
+		} else {
+			prevTo = to.AddDate(-1, 0, 0)
+		}
+	}
+	return &prevFrom, &prevTo, nil
+}
+
+// wholeMonths returns the number of months in the period if it starts on the first day of a month
+// and ends on the last one, or zero otherwise.
+func wholeMonths(from, to time.Time) int {
+	next := to.Add(time.Nanosecond)
+	if from.Day() != 1 || next.Day() != 1 || !next.Equal(time.Date(next.Year(), next.Month(), 1, 0, 0, 0, 0, next.Location())) {
+		return 0
+	}
+	return (next.Year()-from.Year())*12 + int(next.Month()) - int(from.Month())
+}
+
+// calendarDays returns the number of days from the day of from to the day of to, both included, counted by date
+// so that days made shorter or longer by a daylight saving change count as one.
+func calendarDays(from, to time.Time) int {
+	f := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	t := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(t.Sub(f).Hours()/24) + 1
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseComparePeriod(t *testing.T) {
+	tests := []struct {
+		period  []string
+		compare []string
+		want    []string
+	}{
+		{[]string{"2020-04-01", "2020-06-30"}, []string{ComparePrevious}, []string{"2020-01-01", "2020-03-31"}},
+		{[]string{"2020-04-10", "2020-04-19"}, []string{ComparePrevious}, []string{"2020-03-31", "2020-04-09"}},
+		{[]string{"2020-01-01", "2020-02-29"}, []string{CompareYearAgo}, []string{"2019-01-01", "2019-02-28"}},
+		{[]string{"2020-04-10", "2020-04-19"}, []string{CompareYearAgo}, []string{"2019-04-10", "2019-04-19"}},
+		{[]string{"2020-04-01", "2020-06-30"}, []string{"2018-01-01", "2018-03-31"}, []string{"2018-01-01", "2018-03-31"}},
+		{[]string{"2020-04-01", "2020-06-30"}, []string{"2018-Q1"}, []string{"2018-01-01", "2018-03-31"}},
+	}
+	for _, tt := range tests {
+		from, to, err := ParsePeriod(tt.period)
+		if err != nil {
+			t.Fatalf("ParsePeriod(), err=%v", err)
+		}
+		wantFrom, wantTo, err := ParsePeriod(tt.want)
+		if err != nil {
+			t.Fatalf("ParsePeriod(), err=%v", err)
+		}
+
+		gotFrom, gotTo, err := ParseComparePeriod(tt.compare, *from, *to)
+		if err != nil {
+			t.Errorf("ParseComparePeriod(%v, %v), err=%v", tt.compare, tt.period, err)
+			continue
+		}
+		if !gotFrom.Equal(*wantFrom) || !gotTo.Equal(*wantTo) {
+			t.Errorf("ParseComparePeriod(%v, %v) = %s - %s, want %s - %s", tt.compare, tt.period, gotFrom, gotTo, wantFrom, wantTo)
+		}
+	}
+
+	// The previous period has as many days across the end of British Summer Time
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatalf("LoadLocation(), err=%v", err)
+	}
+	from, to, err := ParsePeriodIn([]string{"2020-10-20", "2020-10-30"}, london)
+	if err != nil {
+		t.Fatalf("ParsePeriodIn(), err=%v", err)
+	}
+	wantFrom, wantTo, err := ParsePeriodIn([]string{"2020-10-09", "2020-10-19"}, london)
+	if err != nil {
+		t.Fatalf("ParsePeriodIn(), err=%v", err)
+	}
+	gotFrom, gotTo, err := ParseComparePeriod([]string{ComparePrevious}, *from, *to)
+	if err != nil {
+		t.Fatalf("ParseComparePeriod(), err=%v", err)
+	}
+	if !gotFrom.Equal(*wantFrom) || !gotTo.Equal(*wantTo) {
+		t.Errorf("ParseComparePeriod(previous) in Europe/London = %s - %s, want %s - %s", gotFrom, gotTo, wantFrom, wantTo)
+	}
+
+	if _, _, err := ParseComparePeriod([]string{"last-fortnight"}, time.Time{}, time.Time{}); err == nil {
+		t.Errorf("ParseComparePeriod(last-fortnight), err=nil, want an error")
+	}
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// Interval is the length of the buckets a period is split into.
+type Interval string
+
+const (
+	IntervalDay     Interval = "day"
+	IntervalWeek    Interval = "week"
+	IntervalMonth   Interval = "month"
+	IntervalQuarter Interval = "quarter"
+)
+
+func ParseInterval(s string) (Interval, error) {
+	switch i := Interval(s); i {
+	case "", IntervalDay, IntervalWeek, IntervalMonth, IntervalQuarter:
+		return i, nil
+	default:
+		return "", fmt.Errorf("unknown interval `%s`", s)
+	}
+}
+
+// Bucket is a part of a period.
+type Bucket struct {
+	Label string
+	From  time.Time
+	To    time.Time
+}
+
+// SplitPeriod splits the period into calendar days, ISO weeks, months or quarters.
+// The first and last buckets are cut to the period. An empty interval returns the whole period.
+func SplitPeriod(from, to time.Time, interval Interval) []Bucket {
+	if interval == "" {
+		return []Bucket{{Label: fmt.Sprintf("%s - %s", from.Format(periodLayout), to.Format(periodLayout)), From: from, To: to}}
+	}
+
+	res := []Bucket{}
+	start := intervalStart(from, interval)
+	for !start.After(to) {
+		next := nextIntervalStart(start, interval)
+		b := Bucket{
+			Label: intervalLabel(start, interval),
+			From:  start,
+			To:    next.Add(-time.Nanosecond),
+		}
+		if b.From.Before(from) {
+			b.From = from
+		}
+		if b.To.After(to) {
+			b.To = to
+		}
+		res = append(res, b)
+		start = next
+	}
+	return res
+}
+
+func intervalStart(t time.Time, interval Interval) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch interval {
+	case IntervalWeek:
+		weekday := (int(day.Weekday()) + 6) % 7 // Monday is the first day of the week
+		return day.AddDate(0, 0, -weekday)
+	case IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	case IntervalQuarter:
+		month := time.Month((int(t.Month())-1)/3*3 + 1)
+		return time.Date(t.Year(), month, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return day
+	}
+}
+
+func nextIntervalStart(start time.Time, interval Interval) time.Time {
+	switch interval {
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	case IntervalQuarter:
+		return start.AddDate(0, 3, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func intervalLabel(start time.Time, interval Interval) string {
+	switch interval {
+	case IntervalWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case IntervalMonth:
+		return start.Format("2006-01")
+	case IntervalQuarter:
+		return fmt.Sprintf("%d-Q%d", start.Year(), (int(start.Month())-1)/3+1)
+	default:
+		return start.Format(periodLayout)
+	}
+}
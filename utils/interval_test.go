@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplitPeriod(t *testing.T) {
+	from, to, err := ParsePeriod([]string{"2020-02-15", "2020-07-10"})
+	if err != nil {
+		t.Fatalf("ParsePeriod(), err=%v", err)
+	}
+
+	tests := []struct {
+		interval Interval
+		want     []string
+	}{
+		{IntervalMonth, []string{"2020-02", "2020-03", "2020-04", "2020-05", "2020-06", "2020-07"}},
+		{IntervalQuarter, []string{"2020-Q1", "2020-Q2", "2020-Q3"}},
+	}
+	for _, tt := range tests {
+		got := SplitPeriod(*from, *to, tt.interval)
+		if len(got) != len(tt.want) {
+			t.Fatalf("SplitPeriod(%s) returned %d buckets, want %d", tt.interval, len(got), len(tt.want))
+		}
+		for i, b := range got {
+			if b.Label != tt.want[i] {
+				t.Errorf("SplitPeriod(%s) bucket %d = %s, want %s", tt.interval, i, b.Label, tt.want[i])
+			}
+		}
+		if !got[0].From.Equal(*from) || !got[len(got)-1].To.Equal(*to) {
+			t.Errorf("SplitPeriod(%s) covers %s - %s, want %s - %s", tt.interval, got[0].From, got[len(got)-1].To, from, to)
+		}
+	}
+
+	endOfDay := time.Date(2020, 2, 15, 23, 59, 59, 1e9-1, time.UTC)
+	weeks := SplitPeriod(*from, endOfDay, IntervalWeek)
+	if len(weeks) != 1 || weeks[0].Label != "2020-W07" || !weeks[0].From.Equal(*from) {
+		t.Errorf("SplitPeriod(week) = %v, want a single 2020-W07 bucket from %s", weeks, from)
+	}
+	if !weeks[0].To.Equal(endOfDay) {
+		t.Errorf("SplitPeriod(week) ends %s, want %s", weeks[0].To, endOfDay)
+	}
+}
@@ -2,6 +2,9 @@ package utils
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -9,22 +12,191 @@ const (
 	periodLayout = "2006-01-02"
 )
 
+// Now is the current time relative period expressions are resolved against.
+var Now = time.Now
+
+var (
+	yearExpr         = regexp.MustCompile(`^(\d{4})$`)
+	monthExpr        = regexp.MustCompile(`^(\d{4})-(\d{2})$`)
+	quarterExpr      = regexp.MustCompile(`^(\d{4})-q([1-4])(?:/stagger([1-3]))?$`)
+	taxYearExpr      = regexp.MustCompile(`^(\d{4})/(\d{2})$`)
+	lastDaysExpr     = regexp.MustCompile(`^last-(\d+)-days$`)
+	relativeExpr     = regexp.MustCompile(`^(this|last)-(week|month|quarter|year|tax-year)(?:/stagger([1-3]))?$`)
+	toDateExpr       = regexp.MustCompile(`^(ytd|qtd|mtd)$`)
+	relativeDateExpr = regexp.MustCompile(`^(today|yesterday)$`)
+)
+
+// ParsePeriod parses the `from` and `to` dates of a period, or a single period expression:
+//
+//	2021, 2021-05, 2020-Q3     a calendar year, month or quarter
+//	2020-Q3/stagger2           a VAT quarter of the stagger group 1, 2 or 3 (quarters ending Mar, Apr or May and every 3 months)
+//	2021/22                    a UK tax year, 6 April to 5 April
+//	this-month, last-quarter   the current or previous week, month, quarter, year or tax-year, quarters can have a /stagger
+//	ytd, qtd, mtd              the year, quarter or month to date
+//	last-30-days               the number of whole days up to yesterday
+//	today, yesterday
+//
+// Days start and end at midnight UTC, see ParsePeriodIn.
 func ParsePeriod(period []string) (*time.Time, *time.Time, error) {
-	if len(period) != 2 {
-		return nil, nil, fmt.Errorf("expected `from` and `to` period dates")
-	}
+	return ParsePeriodIn(period, time.UTC)
+}
 
-	from, err := time.Parse(periodLayout, period[0])
-	if err != nil {
-		return nil, nil, fmt.Errorf("error parsing `from` date: %s", err)
+// ParsePeriodIn parses the period as ParsePeriod with days starting and ending at midnight in the location.
+func ParsePeriodIn(period []string, loc *time.Location) (*time.Time, *time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
 	}
 
-	to, err := time.Parse(periodLayout, period[1])
-	if err != nil {
-		return nil, nil, fmt.Errorf("error parsing `to` date: %s", err)
+	switch len(period) {
+	case 1:
+		from, to, err := parsePeriodExpression(strings.ToLower(strings.TrimSpace(period[0])), Now().In(loc))
+		if err != nil {
+			return nil, nil, err
+		}
+		return dayRange(from, to, loc)
+	case 2:
+		from, err := time.Parse(periodLayout, period[0])
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing `from` date: %w", err)
+		}
+
+		to, err := time.Parse(periodLayout, period[1])
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing `to` date: %w", err)
+		}
+
+		return dayRange(from, to, loc)
+	default:
+		return nil, nil, fmt.Errorf("expected `from` and `to` period dates or a period expression")
 	}
+}
 
-	fromMin := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
-	toMax := time.Date(to.Year(), to.Month(), to.Day(), 23, 59, 59, 1e9-1, time.UTC)
+// dayRange returns the start of the first day and the end of the last one in the location.
+func dayRange(from, to time.Time, loc *time.Location) (*time.Time, *time.Time, error) {
+	fromMin := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	toMax := time.Date(to.Year(), to.Month(), to.Day(), 23, 59, 59, 1e9-1, loc)
+	if toMax.Before(fromMin) {
+		return nil, nil, fmt.Errorf("period ends before it starts")
+	}
 	return &fromMin, &toMax, nil
 }
+
+// parsePeriodExpression returns the first and last days of the period, as dates in UTC.
+func parsePeriodExpression(expr string, now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if m := yearExpr.FindStringSubmatch(expr); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		from := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, -1), nil
+	}
+	if m := monthExpr.FindStringSubmatch(expr); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		if month < 1 || month > 12 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid month in `%s`", expr)
+		}
+		from := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 1, -1), nil
+	}
+	if m := quarterExpr.FindStringSubmatch(expr); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		quarter, _ := strconv.Atoi(m[2])
+		from := quarterStart(year, quarter, stagger(m[3]))
+		return from, from.AddDate(0, 3, -1), nil
+	}
+	if m := taxYearExpr.FindStringSubmatch(expr); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		next, _ := strconv.Atoi(m[2])
+		if (year+1)%100 != next {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid tax year `%s`, expected consecutive years (e.g. 2021/22)", expr)
+		}
+		from := time.Date(year, 4, 6, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, -1), nil
+	}
+	if m := lastDaysExpr.FindStringSubmatch(expr); m != nil {
+		days, _ := strconv.Atoi(m[1])
+		if days < 1 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid number of days in `%s`", expr)
+		}
+		return today.AddDate(0, 0, -days), today.AddDate(0, 0, -1), nil
+	}
+	if m := relativeExpr.FindStringSubmatch(expr); m != nil {
+		if m[3] != "" && m[2] != "quarter" {
+			return time.Time{}, time.Time{}, fmt.Errorf("stagger groups only apply to quarters, got `%s`", expr)
+		}
+		from, to := currentPeriod(m[2], stagger(m[3]), today)
+		if m[1] == "last" {
+			from, to = currentPeriod(m[2], stagger(m[3]), from.AddDate(0, 0, -1))
+		}
+		return from, to, nil
+	}
+	if m := toDateExpr.FindStringSubmatch(expr); m != nil {
+		unit := map[string]string{"ytd": "year", "qtd": "quarter", "mtd": "month"}[m[1]]
+		from, _ := currentPeriod(unit, 1, today)
+		return from, today, nil
+	}
+	if m := relativeDateExpr.FindStringSubmatch(expr); m != nil {
+		if m[1] == "yesterday" {
+			today = today.AddDate(0, 0, -1)
+		}
+		return today, today, nil
+	}
+
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown period expression `%s`", expr)
+}
+
+func stagger(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// quarterStart returns the first day of the quarter. Quarters of the stagger group 1 are calendar quarters,
+// the groups 2 and 3 start a month and two months later.
+func quarterStart(year, quarter, stagger int) time.Time {
+	return time.Date(year, time.Month(3*(quarter-1)+stagger), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// currentPeriod returns the first and last days of the week, month, quarter, year or tax year the day is in.
+func currentPeriod(unit string, stagger int, day time.Time) (time.Time, time.Time) {
+	switch unit {
+	case "week":
+		from := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return from, from.AddDate(0, 0, 6)
+	case "month":
+		from := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 1, -1)
+	case "quarter":
+		months := (int(day.Month()) - stagger + 12) % 3
+		from := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -months, 0)
+		return from, from.AddDate(0, 3, -1)
+	case "tax-year":
+		from := time.Date(day.Year(), 4, 6, 0, 0, 0, 0, time.UTC)
+		if day.Before(from) {
+			from = from.AddDate(-1, 0, 0)
+		}
+		return from, from.AddDate(1, 0, -1)
+	default:
+		from := time.Date(day.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, -1)
+	}
+}
+
+func ParseDate(date string) (*time.Time, error) {
+	return ParseDateIn(date, time.UTC)
+}
+
+// ParseDateIn parses the date as midnight in the location.
+func ParseDateIn(date string, loc *time.Location) (*time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	d, err := time.ParseInLocation(periodLayout, date, loc)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
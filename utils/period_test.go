@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePeriodExpression(t *testing.T) {
+	now := Now
+	defer func() { Now = now }()
+	Now = func() time.Time {
+		return time.Date(2021, 5, 19, 15, 4, 5, 0, time.UTC) // a Wednesday
+	}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"2021", []string{"2021-01-01", "2021-12-31"}},
+		{"2021-05", []string{"2021-05-01", "2021-05-31"}},
+		{"2020-Q3", []string{"2020-07-01", "2020-09-30"}},
+		{"2020-Q4/stagger2", []string{"2020-11-01", "2021-01-31"}},
+		{"2020-Q1/stagger3", []string{"2020-03-01", "2020-05-31"}},
+		{"2021/22", []string{"2021-04-06", "2022-04-05"}},
+		{"this-week", []string{"2021-05-17", "2021-05-23"}},
+		{"last-month", []string{"2021-04-01", "2021-04-30"}},
+		{"last-quarter", []string{"2021-01-01", "2021-03-31"}},
+		{"last-quarter/stagger2", []string{"2021-02-01", "2021-04-30"}},
+		{"this-quarter/stagger3", []string{"2021-03-01", "2021-05-31"}},
+		{"last-year", []string{"2020-01-01", "2020-12-31"}},
+		{"last-tax-year", []string{"2020-04-06", "2021-04-05"}},
+		{"ytd", []string{"2021-01-01", "2021-05-19"}},
+		{"qtd", []string{"2021-04-01", "2021-05-19"}},
+		{"last-30-days", []string{"2021-04-19", "2021-05-18"}},
+		{"yesterday", []string{"2021-05-18", "2021-05-18"}},
+	}
+	for _, tt := range tests {
+		gotFrom, gotTo, err := ParsePeriod([]string{tt.expr})
+		if err != nil {
+			t.Errorf("ParsePeriod(%s), err=%v", tt.expr, err)
+			continue
+		}
+		wantFrom, wantTo, err := ParsePeriod(tt.want)
+		if err != nil {
+			t.Fatalf("ParsePeriod(), err=%v", err)
+		}
+		if !gotFrom.Equal(*wantFrom) || !gotTo.Equal(*wantTo) {
+			t.Errorf("ParsePeriod(%s) = %s - %s, want %s - %s", tt.expr, gotFrom, gotTo, wantFrom, wantTo)
+		}
+	}
+
+	for _, expr := range []string{"2021-13", "2021/23", "last-month/stagger2", "next-quarter"} {
+		if _, _, err := ParsePeriod([]string{expr}); err == nil {
+			t.Errorf("ParsePeriod(%s), err=nil, want an error", expr)
+		}
+	}
+}
+
+func TestParsePeriodIn(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("no timezone database: %s", err)
+	}
+
+	from, to, err := ParsePeriodIn([]string{"2020-Q3"}, london)
+	if err != nil {
+		t.Fatalf("ParsePeriodIn(), err=%v", err)
+	}
+	if want := time.Date(2020, 6, 30, 23, 0, 0, 0, time.UTC); !from.Equal(want) {
+		t.Errorf("ParsePeriodIn() from = %s, want %s", from.UTC(), want)
+	}
+	if want := time.Date(2020, 9, 30, 22, 59, 59, 1e9-1, time.UTC); !to.Equal(want) {
+		t.Errorf("ParsePeriodIn() to = %s, want %s", to.UTC(), want)
+	}
+}
@@ -0,0 +1,91 @@
+package vat
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/utils"
+)
+
+// Comparison is the return of a period and the one of the period compared with.
+// The boxes given for the period aren't known for the period compared with, so they aren't compared.
+type Comparison struct {
+	From     time.Time
+	To       time.Time
+	PrevFrom time.Time
+	PrevTo   time.Time
+	// Options are the calculation options of both returns, with the report currency resolved.
+	Options shop.CalcOptions
+
+	Return *Return
+	Prev   *Return
+	// Stores are the returns of each store of a comparison consolidated across stores.
+	Stores      []StoreReturn
+	Methodology []string
+}
+
+// CompareReport calculates the return of the period and the one of the period to compare with.
+func CompareReport(ctx context.Context, r VATReturn, service shop.OrderService, period []string, compare []string, useCached bool, opts shop.CalcOptions) (*Comparison, error) {
+	from, to, err := utils.ParsePeriodIn(period, opts.Location)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing period dates: %w", err)
+	}
+	prevFrom, prevTo, err := utils.ParseComparePeriod(compare, *from, *to)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing period to compare with: %w", err)
+	}
+
+	orders, err := service.ListCreatedBetween(ctx, *from, *to, useCached)
+	if err != nil {
+		return nil, fmt.Errorf("error getting orders: %w", err)
+	}
+	shop.Log.Printf("Found %d orders", len(orders))
+
+	prevOrders, err := service.ListCreatedBetween(ctx, *prevFrom, *prevTo, useCached)
+	if err != nil {
+		return nil, fmt.Errorf("error getting orders: %w", err)
+	}
+	shop.Log.Printf("Found %d orders to compare with", len(prevOrders))
+
+	opts, err = shop.ResolveCurrency(append(append([]*model.Order{}, orders...), prevOrders...), opts)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving report currency: %w", err)
+	}
+
+	cur, err := r.Compute(orders, *from, *to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating VAT return: %w", err)
+	}
+	prev, err := r.Compute(prevOrders, *prevFrom, *prevTo, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating VAT return to compare with: %w", err)
+	}
+
+	stores, err := storeReturns(r, service, orders, *from, *to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating VAT return: %w", err)
+	}
+	prevStores, err := storeReturns(r, service, prevOrders, *prevFrom, *prevTo, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating VAT return to compare with: %w", err)
+	}
+	for i := range stores {
+		stores[i].Prev = prevStores[i].Return
+	}
+
+	return &Comparison{
+		From:     *from,
+		To:       *to,
+		PrevFrom: *prevFrom,
+		PrevTo:   *prevTo,
+		Options:  opts,
+		Return:   cur,
+		Prev:     prev,
+		Stores:   stores,
+
+		Methodology: append(r.Methodology(opts), "Boxes 4, 5 and 7 are worked out from the amounts given for the period, and are left blank for the period compared with."),
+	}, nil
+}
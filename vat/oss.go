@@ -0,0 +1,190 @@
+package vat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/utils"
+	"github.com/shopspring/decimal"
+)
+
+// ErrNoEURRate is returned by Calc when there's neither an EUR exchange rate nor exchange rates to get it from.
+var ErrNoEURRate = errors.New("EUR exchange rate not set, set it or the exchange rates")
+
+// OSSReturn is the EU One-Stop-Shop return of distance sales to EU member states.
+// EURRate converts the report currency to euros, i.e. the ECB rate on the last day of the period.
+// Without it, the exchange rates of the calculation options on that day are used, unless the report is in euros.
+type OSSReturn struct {
+	EURRate decimal.Decimal
+}
+
+// OSSLine holds the supplies to a member state at a VAT rate, in euros.
+type OSSLine struct {
+	Country       model.CountryCode
+	Rate          decimal.Decimal
+	TaxableAmount decimal.Decimal
+	VAT           decimal.Decimal
+	RefundAmount  decimal.Decimal
+	RefundVAT     decimal.Decimal
+}
+
+func (l *OSSLine) NetVAT() decimal.Decimal {
+	return l.VAT.Sub(l.RefundVAT)
+}
+
+// OSSReport is the OSS return of a period, with the orders it's calculated from.
+type OSSReport struct {
+	From time.Time
+	To   time.Time
+	// Options are the calculation options the return is in, with the report currency resolved.
+	Options shop.CalcOptions
+	Orders  []*model.Order
+
+	Lines       []OSSLine
+	Methodology []string
+}
+
+// Total is the net VAT payable of the lines, in euros.
+func (r *OSSReport) Total() decimal.Decimal {
+	total := decimal.Zero
+	for _, l := range r.Lines {
+		total = total.Add(l.NetVAT())
+	}
+	return total
+}
+
+func (s *OSSReturn) Report(ctx context.Context, service shop.OrderService, period []string, useCached bool, opts shop.CalcOptions) (*OSSReport, error) {
+	from, to, err := utils.ParsePeriodIn(period, opts.Location)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing period dates: %w", err)
+	}
+
+	orders, err := service.ListCreatedBetween(ctx, *from, *to, useCached)
+	if err != nil {
+		return nil, fmt.Errorf("error getting orders: %w", err)
+	}
+	shop.Log.Printf("Found %d orders", len(orders))
+
+	opts, err = shop.ResolveCurrency(orders, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving report currency: %w", err)
+	}
+
+	lines, err := s.Calc(orders, *from, *to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating OSS return: %w", err)
+	}
+
+	return &OSSReport{
+		From:        *from,
+		To:          *to,
+		Options:     opts,
+		Orders:      orders,
+		Lines:       lines,
+		Methodology: s.Methodology(opts),
+	}, nil
+}
+
+func (s *OSSReturn) Methodology(opts shop.CalcOptions) []string {
+	return append([]string{
+		"Sales and refunds of orders shipped to EU member states are grouped by country and the order VAT rate, " +
+			"converted to euros at the rate given or the one on the last day of the period, and split into the taxable amount and VAT.",
+	}, opts.Describe()...)
+}
+
+// Calc groups the sales and refunds to EU member states by country and VAT rate.
+func (s *OSSReturn) Calc(orders []*model.Order, from, to time.Time, opts shop.CalcOptions) ([]OSSLine, error) {
+	eurRate := s.EURRate
+	if eurRate.IsZero() && opts.Currency == model.CurrencyCodeEur {
+		eurRate = decimal.NewFromInt(1)
+	}
+	if eurRate.IsZero() {
+		if opts.Rates == nil || opts.Currency == "" {
+			return nil, ErrNoEURRate
+		}
+		rate, err := opts.Rates.Rate(opts.Currency, model.CurrencyCodeEur, to)
+		if err != nil {
+			return nil, fmt.Errorf("error getting EUR exchange rate: %w", err)
+		}
+		eurRate = rate
+	}
+
+	type key struct {
+		country model.CountryCode
+		rate    string
+	}
+	lines := map[key]*OSSLine{}
+
+	for _, o := range orders {
+		country := shop.GetOrderCountryCode(o)
+		if !shop.IsEUCountry(country) {
+			continue
+		}
+
+		sales, err := shop.CalcOrderSales(o, from, to, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error getting order %s sales: %w", o.Name, err)
+		}
+		refunds, err := shop.CalcOrderRefunds(o, from, to, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error getting order %s refunds: %w", o.Name, err)
+		}
+		if sales.IsZero() && refunds.IsZero() {
+			continue
+		}
+
+		rate := getOrderTaxRate(o)
+		k := key{country: *country, rate: rate.String()}
+		l, ok := lines[k]
+		if !ok {
+			l = &OSSLine{
+				Country: *country,
+				Rate:    rate.Mul(decimal.NewFromInt(100)),
+			}
+			lines[k] = l
+		}
+
+		net, tax := splitTax(sales.Mul(eurRate), rate)
+		l.TaxableAmount = l.TaxableAmount.Add(net)
+		l.VAT = l.VAT.Add(tax)
+
+		net, tax = splitTax(refunds.Mul(eurRate), rate)
+		l.RefundAmount = l.RefundAmount.Add(net)
+		l.RefundVAT = l.RefundVAT.Add(tax)
+	}
+
+	res := []OSSLine{}
+	for _, l := range lines {
+		res = append(res, *l)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Country != res[j].Country {
+			return res[i].Country < res[j].Country
+		}
+		return res[i].Rate.LessThan(res[j].Rate)
+	})
+
+	return res, nil
+}
+
+func getOrderTaxRate(o *model.Order) decimal.Decimal {
+	rate := decimal.Zero
+	for _, t := range o.TaxLines {
+		if t.Rate == nil {
+			continue
+		}
+		rate = rate.Add(decimal.NewFromFloat(*t.Rate))
+	}
+	return rate
+}
+
+// splitTax splits a VAT inclusive amount into its net value and VAT.
+func splitTax(gross decimal.Decimal, rate decimal.Decimal) (decimal.Decimal, decimal.Decimal) {
+	net := gross.Div(decimal.NewFromInt(1).Add(rate)).Round(2)
+	return net, gross.Round(2).Sub(net)
+}
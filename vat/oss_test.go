@@ -0,0 +1,99 @@
+package vat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/shop/shoptest"
+	"github.com/shopspring/decimal"
+)
+
+func TestOSSReturnCalc(t *testing.T) {
+	from := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 9, 30, 23, 59, 59, 0, time.UTC)
+
+	refund := newSale("11.90", time.Date(2021, 8, 15, 10, 0, 0, 0, time.UTC))
+	refund.Kind = model.OrderTransactionKindRefund
+
+	orders := []*model.Order{
+		{
+			ShippingAddress: &model.MailingAddress{CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeFr)},
+			TaxLines:        []model.TaxLine{{Rate: shoptest.NewFloat64(0.2)}},
+			Transactions:    []model.OrderTransaction{newSale("120.00", time.Date(2021, 7, 10, 10, 0, 0, 0, time.UTC))},
+		},
+		{
+			ShippingAddress: &model.MailingAddress{CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeDe)},
+			TaxLines:        []model.TaxLine{{Rate: shoptest.NewFloat64(0.19)}},
+			Transactions: []model.OrderTransaction{
+				newSale("119.00", time.Date(2021, 8, 10, 10, 0, 0, 0, time.UTC)),
+				refund,
+			},
+		},
+		{
+			ShippingAddress: &model.MailingAddress{CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeGb)},
+			TaxLines:        []model.TaxLine{{Rate: shoptest.NewFloat64(0.2)}},
+			Transactions:    []model.OrderTransaction{newSale("60.00", time.Date(2021, 8, 10, 10, 0, 0, 0, time.UTC))},
+		},
+	}
+
+	s := OSSReturn{EURRate: decimal.RequireFromString("1.2")}
+	got, err := s.Calc(orders, from, to, shop.CalcOptions{})
+	if err != nil {
+		t.Fatalf("Calc(), err=%v", err)
+	}
+
+	want := []OSSLine{
+		{
+			Country:       model.CountryCodeDe,
+			Rate:          decimal.RequireFromString("19"),
+			TaxableAmount: decimal.RequireFromString("120.00"),
+			VAT:           decimal.RequireFromString("22.80"),
+			RefundAmount:  decimal.RequireFromString("12.00"),
+			RefundVAT:     decimal.RequireFromString("2.28"),
+		},
+		{
+			Country:       model.CountryCodeFr,
+			Rate:          decimal.RequireFromString("20"),
+			TaxableAmount: decimal.RequireFromString("120.00"),
+			VAT:           decimal.RequireFromString("24.00"),
+		},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Calc() = %d lines, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Country != w.Country || !g.Rate.Equal(w.Rate) || !g.TaxableAmount.Equal(w.TaxableAmount) || !g.VAT.Equal(w.VAT) || !g.RefundAmount.Equal(w.RefundAmount) || !g.RefundVAT.Equal(w.RefundVAT) {
+			t.Errorf("Calc()[%d] = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestOSSReturnCalcInEuros(t *testing.T) {
+	from := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 9, 30, 23, 59, 59, 0, time.UTC)
+
+	orders := []*model.Order{
+		{
+			ShippingAddress: &model.MailingAddress{CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeFr)},
+			TaxLines:        []model.TaxLine{{Rate: shoptest.NewFloat64(0.2)}},
+			Transactions:    []model.OrderTransaction{newSale("120.00", time.Date(2021, 7, 10, 10, 0, 0, 0, time.UTC))},
+		},
+	}
+
+	s := OSSReturn{}
+	got, err := s.Calc(orders, from, to, shop.CalcOptions{Currency: model.CurrencyCodeEur})
+	if err != nil {
+		t.Fatalf("Calc() in EUR without rates, err=%v", err)
+	}
+	if len(got) != 1 || !got[0].TaxableAmount.Equal(decimal.RequireFromString("100.00")) {
+		t.Errorf("Calc() in EUR = %+v, want a taxable amount of 100.00", got)
+	}
+
+	_, err = s.Calc(orders, from, to, shop.CalcOptions{Currency: model.CurrencyCodeGbp})
+	if err == nil {
+		t.Errorf("Calc() in GBP without rates, want an error")
+	}
+}
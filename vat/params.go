@@ -0,0 +1,119 @@
+package vat
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/r0busta/go-shopify-reports/utils"
+	"github.com/shopspring/decimal"
+)
+
+// Schemes of the returns filed with HMRC.
+const (
+	SchemeFlat     = "flat"
+	SchemeStandard = "standard"
+)
+
+// Params are the return figures and scheme settings that aren't in the store data, as given on the command line
+// or in a request. Dates are in the timezone periods are in.
+type Params struct {
+	Reclaimed string
+	Purchases string
+
+	FlatRate     string
+	RegisteredOn string
+	LimitedCost  []string
+
+	EURRate string
+}
+
+// NewReturn returns the flat or standard scheme return with the params.
+func NewReturn(scheme string, p Params, loc *time.Location) (VATReturn, error) {
+	reclaimed, err := parseAmount(p.Reclaimed)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing reclaimed VAT: %w", err)
+	}
+
+	switch scheme {
+	case SchemeFlat:
+		r, err := newFlatRateReturn(p, loc)
+		if err != nil {
+			return nil, err
+		}
+		r.VATReclaimed = reclaimed
+		return r, nil
+	case SchemeStandard:
+		purchases, err := parseAmount(p.Purchases)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing purchases: %w", err)
+		}
+		return &StandardReturn{
+			VATReclaimed: reclaimed,
+			Purchases:    purchases,
+		}, nil
+	case "oss":
+		return nil, fmt.Errorf("OSS returns are filed with the EU member state of identification, not HMRC")
+	default:
+		return nil, fmt.Errorf("unimplemented scheme `%s`", scheme)
+	}
+}
+
+func newFlatRateReturn(p Params, loc *time.Location) (*FlatRateReturn, error) {
+	if p.FlatRate == "" {
+		return nil, fmt.Errorf("flat rate percentage is required for the flat scheme")
+	}
+	rate, err := decimal.NewFromString(p.FlatRate)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing flat rate: %w", err)
+	}
+
+	r := &FlatRateReturn{
+		Rate: rate,
+	}
+
+	if p.RegisteredOn != "" {
+		r.RegisteredOn, err = utils.ParseDateIn(p.RegisteredOn, loc)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing registration date: %w", err)
+		}
+	}
+
+	if len(p.LimitedCost) > 0 {
+		if len(p.LimitedCost) != 2 {
+			return nil, fmt.Errorf("expected limited cost trader start and end dates")
+		}
+		r.LimitedCostFrom, err = utils.ParseDateIn(p.LimitedCost[0], loc)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing limited cost trader start date: %w", err)
+		}
+		r.LimitedCostTo, err = utils.ParseDateIn(p.LimitedCost[1], loc)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing limited cost trader end date: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+// NewOSSReturn returns the OSS return with the params, converting to euros with the rate if it's given.
+func NewOSSReturn(p Params) (*OSSReturn, error) {
+	if p.EURRate == "" {
+		return &OSSReturn{}, nil
+	}
+	rate, err := decimal.NewFromString(p.EURRate)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing EUR exchange rate: %w", err)
+	}
+
+	return &OSSReturn{
+		EURRate: rate,
+	}, nil
+}
+
+// parseAmount parses an optional amount, zero if it's empty.
+func parseAmount(s string) (decimal.Decimal, error) {
+	if s == "" {
+		return decimal.Zero, nil
+	}
+	return decimal.NewFromString(s)
+}
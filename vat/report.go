@@ -1,38 +1,228 @@
 package vat
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
 	"github.com/r0busta/go-shopify-reports/shop"
 	"github.com/r0busta/go-shopify-reports/utils"
+	"github.com/shopspring/decimal"
+)
 
-	log "github.com/sirupsen/logrus"
+var (
+	LimitedCostTraderRate = decimal.RequireFromString("16.5")
+	FirstYearDiscount     = decimal.NewFromInt(1)
 )
 
 type VATReturn interface {
-	Report(period []string)
+	Report(ctx context.Context, service shop.OrderService, period []string, useCached bool, opts shop.CalcOptions) (*Report, error)
+	Compute(orders []*model.Order, from, to time.Time, opts shop.CalcOptions) (*Return, error)
+	Methodology(opts shop.CalcOptions) []string
+}
+
+// Report is the return of a period under a scheme, with the orders it's calculated from.
+type Report struct {
+	Scheme string
+	From   time.Time
+	To     time.Time
+	// Options are the calculation options the return is in, with the report currency resolved.
+	Options shop.CalcOptions
+	Orders  []*model.Order
+
+	Return *Return
+	// Segments are the parts of the period a single flat rate applies to, flat rate scheme only.
+	Segments []FlatRateSegment
+	// Stores are the returns of each store of a report consolidated across stores.
+	Stores      []StoreReturn
+	Methodology []string
 }
 
+// StoreReturn is a store's return of a report consolidated across stores.
+// The boxes worked out from the amounts given are for all the stores, so they're only meaningful in the consolidated return.
+type StoreReturn struct {
+	Store  string
+	Return *Return
+	// Prev is the store's return in the period compared with, if any.
+	Prev *Return
+}
+
+// storeReturns computes the return of each store of a report consolidated across stores, none if the service is a single store.
+func storeReturns(r VATReturn, service shop.OrderService, orders []*model.Order, from, to time.Time, opts shop.CalcOptions) ([]StoreReturn, error) {
+	stores, ok := service.(*shop.MultiStoreService)
+	if !ok {
+		return nil, nil
+	}
+
+	res := []StoreReturn{}
+	byStore := stores.ByStore(orders)
+	for i, name := range stores.Stores() {
+		ret, err := r.Compute(byStore[i], from, to, opts)
+		if err != nil {
+			return nil, fmt.Errorf("store %s: %w", name, err)
+		}
+		res = append(res, StoreReturn{Store: name, Return: ret})
+	}
+	return res, nil
+}
+
+// FlatRateReturn is the flat rate scheme return. Rate is the sector percentage,
+// replaced by the limited cost trader rate within LimitedCostFrom and LimitedCostTo,
+// and discounted by 1% in the first year after RegisteredOn.
 type FlatRateReturn struct {
+	Rate            decimal.Decimal
+	RegisteredOn    *time.Time
+	LimitedCostFrom *time.Time
+	LimitedCostTo   *time.Time
+	VATReclaimed    decimal.Decimal
 }
 
-func (s *FlatRateReturn) Report(period []string, useCached bool) {
-	from, to, err := utils.ParsePeriod(period)
+var _ VATReturn = &FlatRateReturn{}
+
+// FlatRateSegment is a part of the period where a single flat rate applies.
+type FlatRateSegment struct {
+	From     time.Time
+	To       time.Time
+	Turnover decimal.Decimal
+	Rate     decimal.Decimal
+	VAT      decimal.Decimal
+}
+
+func (s *FlatRateReturn) Report(ctx context.Context, service shop.OrderService, period []string, useCached bool, opts shop.CalcOptions) (*Report, error) {
+	from, to, err := utils.ParsePeriodIn(period, opts.Location)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing period dates: %w", err)
+	}
+
+	orders, err := service.ListCreatedBetween(ctx, *from, *to, useCached)
 	if err != nil {
-		log.Fatalln("error parsing period dates:", err)
+		return nil, fmt.Errorf("error getting orders: %w", err)
 	}
+	shop.Log.Printf("Found %d orders", len(orders))
 
-	shopClient := shop.NewClient()
-	orders, err := shopClient.Order.ListCreatedBetween(*from, *to, useCached)
+	opts, err = shop.ResolveCurrency(orders, opts)
 	if err != nil {
-		log.Fatalf("error getting orders: %s", err)
+		return nil, fmt.Errorf("error resolving report currency: %w", err)
 	}
-	log.Printf("Found %d orders", len(orders))
 
-	totalTurnover, err := shop.CalcTotalTurnover(orders, *from, *to)
+	r, segments, err := s.Calc(orders, *from, *to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating VAT return: %w", err)
+	}
+	stores, err := storeReturns(s, service, orders, *from, *to, opts)
 	if err != nil {
-		log.Fatalf("Error calculating total turnover: %s", err)
+		return nil, fmt.Errorf("error calculating VAT return: %w", err)
 	}
 
-	fmt.Println("Total turnover, including VAT and EC sales (box 6):", totalTurnover.String())
+	return &Report{
+		Scheme:      SchemeFlat,
+		From:        *from,
+		To:          *to,
+		Options:     opts,
+		Orders:      orders,
+		Return:      r,
+		Segments:    segments,
+		Stores:      stores,
+		Methodology: s.Methodology(opts),
+	}, nil
+}
+
+func (s *FlatRateReturn) Compute(orders []*model.Order, from, to time.Time, opts shop.CalcOptions) (*Return, error) {
+	r, _, err := s.Calc(orders, from, to, opts)
+	return r, err
+}
+
+func (s *FlatRateReturn) Methodology(opts shop.CalcOptions) []string {
+	return append([]string{
+		"Box 6 is the turnover including VAT. Box 1 applies the flat rate of each part of the period to its turnover: " +
+			"the sector rate, or the 16.5% limited cost trader rate, less 1% in the first year of VAT registration.",
+		"Box 4 is the VAT reclaimed on capital expenditure goods as given.",
+	}, opts.Describe()...)
+}
+
+// Calc applies the flat rates to the VAT inclusive turnover of each period segment.
+func (s *FlatRateReturn) Calc(orders []*model.Order, from, to time.Time, opts shop.CalcOptions) (*Return, []FlatRateSegment, error) {
+	if s.Rate.IsZero() {
+		return nil, nil, fmt.Errorf("flat rate percentage not set")
+	}
+
+	segments := []FlatRateSegment{}
+	r := &Return{
+		VATReclaimedCurrPeriod: s.VATReclaimed,
+	}
+
+	for _, seg := range s.segments(from, to) {
+		turnover, err := shop.CalcTotalTurnover(orders, seg.From, seg.To, opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("calculating turnover: %w", err)
+		}
+		seg.Turnover = *turnover
+		seg.VAT = turnover.Mul(seg.Rate).Div(decimal.NewFromInt(100)).Round(2)
+		segments = append(segments, seg)
+
+		r.VATDueSales = r.VATDueSales.Add(seg.VAT)
+		r.TotalValueSalesExVAT = r.TotalValueSalesExVAT.Add(seg.Turnover)
+	}
+
+	r.TotalVATDue = r.VATDueSales.Add(r.VATDueAcquisitions)
+	r.NetVATDue = r.TotalVATDue.Sub(r.VATReclaimedCurrPeriod).Abs()
+
+	return r, segments, nil
+}
+
+// segments splits the period where the first year discount or the limited cost trader rate starts or stops applying.
+func (s *FlatRateReturn) segments(from, to time.Time) []FlatRateSegment {
+	bounds := []time.Time{}
+	if s.RegisteredOn != nil {
+		bounds = append(bounds, *s.RegisteredOn, s.RegisteredOn.AddDate(1, 0, 0))
+	}
+	if s.LimitedCostFrom != nil {
+		bounds = append(bounds, *s.LimitedCostFrom)
+	}
+	if s.LimitedCostTo != nil {
+		bounds = append(bounds, s.LimitedCostTo.AddDate(0, 0, 1))
+	}
+
+	res := []FlatRateSegment{}
+	start := from
+	for !start.After(to) {
+		end := to
+		for _, b := range bounds {
+			if b.After(start) && !b.After(end) {
+				end = b.Add(-time.Nanosecond)
+			}
+		}
+		res = append(res, FlatRateSegment{
+			From: start,
+			To:   end,
+			Rate: s.rateOn(start),
+		})
+		start = end.Add(time.Nanosecond)
+	}
+	return res
+}
+
+func (s *FlatRateReturn) rateOn(t time.Time) decimal.Decimal {
+	rate := s.Rate
+	if s.isLimitedCostTrader(t) {
+		rate = LimitedCostTraderRate
+	}
+	if s.RegisteredOn != nil && !t.Before(*s.RegisteredOn) && t.Before(s.RegisteredOn.AddDate(1, 0, 0)) {
+		rate = rate.Sub(FirstYearDiscount)
+	}
+	return rate
+}
+
+func (s *FlatRateReturn) isLimitedCostTrader(t time.Time) bool {
+	if s.LimitedCostFrom == nil && s.LimitedCostTo == nil {
+		return false
+	}
+	if s.LimitedCostFrom != nil && t.Before(*s.LimitedCostFrom) {
+		return false
+	}
+	if s.LimitedCostTo != nil && !t.Before(s.LimitedCostTo.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
 }
@@ -0,0 +1,133 @@
+package vat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/shop/shoptest"
+	"github.com/shopspring/decimal"
+)
+
+func newDate(year int, month time.Month, day int) *time.Time {
+	d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &d
+}
+
+func TestFlatRateReturnCalc(t *testing.T) {
+	from := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 6, 30, 23, 59, 59, 1e9-1, time.UTC)
+
+	orders := []*model.Order{
+		{Transactions: []model.OrderTransaction{newSale("100.00", time.Date(2020, 4, 10, 10, 0, 0, 0, time.UTC))}},
+		{Transactions: []model.OrderTransaction{newSale("200.00", time.Date(2020, 5, 20, 10, 0, 0, 0, time.UTC))}},
+		{Transactions: []model.OrderTransaction{newSale("100.00", time.Date(2020, 6, 10, 10, 0, 0, 0, time.UTC))}},
+	}
+
+	s := FlatRateReturn{
+		Rate:            decimal.RequireFromString("7.5"),
+		RegisteredOn:    newDate(2019, 5, 15),
+		LimitedCostFrom: newDate(2020, 6, 1),
+		LimitedCostTo:   newDate(2020, 12, 31),
+	}
+	got, segments, err := s.Calc(orders, from, to, shop.CalcOptions{})
+	if err != nil {
+		t.Fatalf("Calc(), err=%v", err)
+	}
+
+	wantRates := []string{"6.5", "7.5", "16.5"}
+	if len(segments) != len(wantRates) {
+		t.Fatalf("Calc() = %d segments, want %d", len(segments), len(wantRates))
+	}
+	for i, seg := range segments {
+		if !seg.Rate.Equal(decimal.RequireFromString(wantRates[i])) {
+			t.Errorf("segment %d rate = %s, want %s", i, seg.Rate.String(), wantRates[i])
+		}
+	}
+	if !segments[1].From.Equal(*newDate(2020, 5, 15)) {
+		t.Errorf("first year discount ends %v, want 2020-05-15", segments[1].From)
+	}
+
+	if want := decimal.RequireFromString("38.00"); !got.VATDueSales.Equal(want) {
+		t.Errorf("box 1 = %s, want %s", got.VATDueSales.String(), want.String())
+	}
+	if want := decimal.RequireFromString("38.00"); !got.NetVATDue.Equal(want) {
+		t.Errorf("box 5 = %s, want %s", got.NetVATDue.String(), want.String())
+	}
+	if want := decimal.RequireFromString("400.00"); !got.TotalValueSalesExVAT.Equal(want) {
+		t.Errorf("box 6 = %s, want %s", got.TotalValueSalesExVAT.String(), want.String())
+	}
+}
+
+func TestFlatRateReturnReport(t *testing.T) {
+	at := time.Date(2020, 4, 10, 10, 0, 0, 0, time.UTC)
+	orders := &shoptest.OrderService{Orders: []*model.Order{
+		{CreatedAt: at.Format(shop.ISO8601Layout), Transactions: []model.OrderTransaction{newSale("100.00", at)}},
+	}}
+	s := &FlatRateReturn{Rate: decimal.RequireFromString("7.5")}
+	opts := shop.CalcOptions{Location: time.UTC}
+
+	res, err := s.Report(context.Background(), orders, []string{"2020-04"}, false, opts)
+	if err != nil {
+		t.Fatalf("Report(), err=%v", err)
+	}
+	if want := decimal.RequireFromString("7.50"); !res.Return.VATDueSales.Equal(want) {
+		t.Errorf("box 1 = %s, want %s", res.Return.VATDueSales.String(), want.String())
+	}
+	if len(res.Segments) != 1 || len(res.Orders) != 1 {
+		t.Errorf("Report() = %d segments of %d orders, want 1 of 1", len(res.Segments), len(res.Orders))
+	}
+
+	_, err = s.Report(context.Background(), orders, []string{"2020-13"}, false, opts)
+	if err == nil {
+		t.Errorf("Report() with an invalid period, want error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.Report(ctx, orders, []string{"2020-04"}, false, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Report() with a canceled context, err=%v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCompareReportGivenBoxes(t *testing.T) {
+	orders := &shoptest.OrderService{}
+	for _, at := range []time.Time{time.Date(2020, 3, 10, 10, 0, 0, 0, time.UTC), time.Date(2020, 4, 10, 10, 0, 0, 0, time.UTC)} {
+		orders.Orders = append(orders.Orders, &model.Order{CreatedAt: at.Format(shop.ISO8601Layout), Transactions: []model.OrderTransaction{newSale("120.00", at)}})
+	}
+	r := &FlatRateReturn{Rate: decimal.RequireFromString("10"), VATReclaimed: decimal.RequireFromString("5.00")}
+	opts := shop.CalcOptions{Location: time.UTC}
+
+	res, err := CompareReport(context.Background(), r, orders, []string{"2020-04"}, []string{"previous"}, false, opts)
+	if err != nil {
+		t.Fatalf("CompareReport(), err=%v", err)
+	}
+
+	want := map[int]struct {
+		cur, prev string
+		given     bool
+	}{
+		1: {"12.00", "12.00", false},
+		4: {"5.00", "5.00", true},
+		5: {"7.00", "7.00", true},
+		6: {"120.00", "120.00", false},
+		7: {"0.00", "0.00", true},
+	}
+	prevBoxes := res.Prev.Boxes()
+	for i, b := range res.Return.Boxes() {
+		w, ok := want[b.Number]
+		if !ok {
+			continue
+		}
+		if b.Value.StringFixed(2) != w.cur || prevBoxes[i].Value.StringFixed(2) != w.prev {
+			t.Errorf("box %d = %s/%s, want %s/%s", b.Number, b.Value.StringFixed(2), prevBoxes[i].Value.StringFixed(2), w.cur, w.prev)
+		}
+		if b.Given() != w.given {
+			t.Errorf("box %d given = %v, want %v", b.Number, b.Given(), w.given)
+		}
+	}
+}
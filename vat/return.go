@@ -0,0 +1,62 @@
+package vat
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/shopspring/decimal"
+)
+
+// Return holds the nine boxes of a UK VAT return.
+type Return struct {
+	VATDueSales                  decimal.Decimal // box 1
+	VATDueAcquisitions           decimal.Decimal // box 2
+	TotalVATDue                  decimal.Decimal // box 3
+	VATReclaimedCurrPeriod       decimal.Decimal // box 4
+	NetVATDue                    decimal.Decimal // box 5
+	TotalValueSalesExVAT         decimal.Decimal // box 6
+	TotalValuePurchasesExVAT     decimal.Decimal // box 7
+	TotalValueGoodsSuppliedExVAT decimal.Decimal // box 8
+	TotalAcquisitionsExVAT       decimal.Decimal // box 9
+}
+
+// Box is a numbered box of a return.
+type Box struct {
+	Number int
+	Label  string
+	Value  decimal.Decimal
+}
+
+// Boxes lists the return boxes in order.
+func (r *Return) Boxes() []Box {
+	return []Box{
+		{1, "VAT due on sales and other outputs", r.VATDueSales},
+		{2, "VAT due on acquisitions from EC member states", r.VATDueAcquisitions},
+		{3, "Total VAT due", r.TotalVATDue},
+		{4, "VAT reclaimed on purchases and other inputs", r.VATReclaimedCurrPeriod},
+		{5, "Net VAT to pay to HMRC or reclaim", r.NetVATDue},
+		{6, "Total value of sales and all other outputs, excluding VAT", r.TotalValueSalesExVAT},
+		{7, "Total value of purchases and all other inputs, excluding VAT", r.TotalValuePurchasesExVAT},
+		{8, "Total value of supplies of goods to EC member states, excluding VAT", r.TotalValueGoodsSuppliedExVAT},
+		{9, "Total value of acquisitions of goods from EC member states, excluding VAT", r.TotalAcquisitionsExVAT},
+	}
+}
+
+// givenBoxes are the boxes worked out from the reclaimed VAT and purchases given for the period, rather than from the orders.
+var givenBoxes = map[int]bool{4: true, 5: true, 7: true}
+
+// Given tells if the box is worked out from the amounts given for the period, which aren't known for other periods.
+func (b Box) Given() bool {
+	return givenBoxes[b.Number]
+}
+
+// Print writes the boxes to w, one per line.
+func (r *Return) Print(w io.Writer) error {
+	for _, b := range r.Boxes() {
+		_, err := fmt.Fprintf(w, "%s (box %d): %s\n", b.Label, b.Number, b.Value.StringFixed(2))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package vat
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/utils"
+	"github.com/shopspring/decimal"
+)
+
+// StandardReturn is the standard VAT accounting scheme return.
+// Purchases aren't in the store data, so boxes 4 and 7 are taken as given.
+type StandardReturn struct {
+	VATReclaimed decimal.Decimal
+	Purchases    decimal.Decimal
+}
+
+var _ VATReturn = &StandardReturn{}
+
+func (s *StandardReturn) Report(ctx context.Context, service shop.OrderService, period []string, useCached bool, opts shop.CalcOptions) (*Report, error) {
+	from, to, err := utils.ParsePeriodIn(period, opts.Location)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing period dates: %w", err)
+	}
+
+	orders, err := service.ListCreatedBetween(ctx, *from, *to, useCached)
+	if err != nil {
+		return nil, fmt.Errorf("error getting orders: %w", err)
+	}
+	shop.Log.Printf("Found %d orders", len(orders))
+
+	opts, err = shop.ResolveCurrency(orders, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving report currency: %w", err)
+	}
+
+	r, err := s.Calc(orders, *from, *to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating VAT return: %w", err)
+	}
+	stores, err := storeReturns(s, service, orders, *from, *to, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating VAT return: %w", err)
+	}
+
+	return &Report{
+		Scheme:      SchemeStandard,
+		From:        *from,
+		To:          *to,
+		Options:     opts,
+		Orders:      orders,
+		Return:      r,
+		Stores:      stores,
+		Methodology: s.Methodology(opts),
+	}, nil
+}
+
+func (s *StandardReturn) Compute(orders []*model.Order, from, to time.Time, opts shop.CalcOptions) (*Return, error) {
+	return s.Calc(orders, from, to, opts)
+}
+
+func (s *StandardReturn) Methodology(opts shop.CalcOptions) []string {
+	return append([]string{
+		"Box 6 is the turnover excluding the VAT of the order tax rates, whatever the destination, " +
+			"and box 1 the VAT included in the turnover of orders shipped to the UK. " +
+			"Box 8 is the turnover excluding VAT of orders shipped to EU member states.",
+		"Boxes 4 and 7 are the VAT reclaimed and purchases as given, boxes 2 and 9 are nil.",
+	}, opts.Describe()...)
+}
+
+// Calc computes the return from the order sales and refunds in the period.
+// Acquisitions from EC member states are purchases, so boxes 2 and 9 are always zero.
+func (s *StandardReturn) Calc(orders []*model.Order, from, to time.Time, opts shop.CalcOptions) (*Return, error) {
+	r := &Return{
+		VATReclaimedCurrPeriod:   s.VATReclaimed,
+		TotalValuePurchasesExVAT: s.Purchases,
+	}
+
+	for _, o := range orders {
+		tax, err := shop.CalcOrderSaleTax(o, from, to, opts)
+		if err != nil {
+			return nil, fmt.Errorf("calculating order %s tax: %w", o.Name, err)
+		}
+		r.VATDueSales = r.VATDueSales.Add(*tax)
+
+		// Boxes 6 and 8 exclude the VAT charged at the order rates, including other countries' VAT
+		income, err := shop.CalcOrderTurnoverExTax(o, from, to, opts)
+		if err != nil {
+			return nil, fmt.Errorf("calculating order %s turnover excluding VAT: %w", o.Name, err)
+		}
+		r.TotalValueSalesExVAT = r.TotalValueSalesExVAT.Add(*income)
+
+		if shop.IsEUCountry(shop.GetOrderCountryCode(o)) {
+			r.TotalValueGoodsSuppliedExVAT = r.TotalValueGoodsSuppliedExVAT.Add(*income)
+		}
+	}
+
+	r.TotalVATDue = r.VATDueSales.Add(r.VATDueAcquisitions)
+	r.NetVATDue = r.TotalVATDue.Sub(r.VATReclaimedCurrPeriod).Abs()
+
+	return r, nil
+}
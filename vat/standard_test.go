@@ -0,0 +1,89 @@
+package vat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/r0busta/go-shopify-graphql-model/v3/graph/model"
+	"github.com/r0busta/go-shopify-reports/shop"
+	"github.com/r0busta/go-shopify-reports/shop/shoptest"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v4"
+)
+
+func newSale(amount string, processedAt time.Time) model.OrderTransaction {
+	return model.OrderTransaction{
+		ProcessedAt: model.NewString(processedAt.Format(shop.ISO8601Layout)),
+		Kind:        model.OrderTransactionKindSale,
+		Status:      model.OrderTransactionStatusSuccess,
+		AmountSet: &model.MoneyBag{
+			ShopMoney: &model.MoneyV2{
+				Amount: null.StringFrom(amount),
+			},
+		},
+	}
+}
+
+func TestStandardReturnCalc(t *testing.T) {
+	from := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 6, 30, 23, 59, 59, 0, time.UTC)
+
+	orders := []*model.Order{
+		{
+			ShippingAddress: &model.MailingAddress{CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeGb)},
+			TaxLines:        []model.TaxLine{{Rate: shoptest.NewFloat64(0.2)}},
+			Transactions:    []model.OrderTransaction{newSale("12.00", time.Date(2020, 4, 1, 10, 0, 0, 0, time.UTC))},
+		},
+		{
+			ShippingAddress: &model.MailingAddress{CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeFr)},
+			Transactions:    []model.OrderTransaction{newSale("50.00", time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC))},
+		},
+		{
+			ShippingAddress: &model.MailingAddress{CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeDe)},
+			TaxLines:        []model.TaxLine{{Rate: shoptest.NewFloat64(0.19)}},
+			Transactions:    []model.OrderTransaction{newSale("119.00", time.Date(2020, 5, 2, 10, 0, 0, 0, time.UTC))},
+		},
+		{
+			ShippingAddress: &model.MailingAddress{CountryCodeV2: shoptest.NewCountryCode(model.CountryCodeGb)},
+			TaxLines:        []model.TaxLine{{Rate: shoptest.NewFloat64(0.2)}},
+			Transactions:    []model.OrderTransaction{newSale("24.00", time.Date(2020, 7, 1, 10, 0, 0, 0, time.UTC))},
+		},
+	}
+
+	s := StandardReturn{
+		VATReclaimed: decimal.RequireFromString("1.50"),
+		Purchases:    decimal.RequireFromString("7.50"),
+	}
+	got, err := s.Calc(orders, from, to, shop.CalcOptions{})
+	if err != nil {
+		t.Fatalf("Calc(), err=%v", err)
+	}
+
+	want := Return{
+		VATDueSales:                  decimal.RequireFromString("2.00"),
+		TotalVATDue:                  decimal.RequireFromString("2.00"),
+		VATReclaimedCurrPeriod:       decimal.RequireFromString("1.50"),
+		NetVATDue:                    decimal.RequireFromString("0.50"),
+		TotalValueSalesExVAT:         decimal.RequireFromString("160.00"),
+		TotalValuePurchasesExVAT:     decimal.RequireFromString("7.50"),
+		TotalValueGoodsSuppliedExVAT: decimal.RequireFromString("150.00"),
+	}
+	for _, c := range []struct {
+		box       string
+		got, want decimal.Decimal
+	}{
+		{"1", got.VATDueSales, want.VATDueSales},
+		{"2", got.VATDueAcquisitions, want.VATDueAcquisitions},
+		{"3", got.TotalVATDue, want.TotalVATDue},
+		{"4", got.VATReclaimedCurrPeriod, want.VATReclaimedCurrPeriod},
+		{"5", got.NetVATDue, want.NetVATDue},
+		{"6", got.TotalValueSalesExVAT, want.TotalValueSalesExVAT},
+		{"7", got.TotalValuePurchasesExVAT, want.TotalValuePurchasesExVAT},
+		{"8", got.TotalValueGoodsSuppliedExVAT, want.TotalValueGoodsSuppliedExVAT},
+		{"9", got.TotalAcquisitionsExVAT, want.TotalAcquisitionsExVAT},
+	} {
+		if !c.got.Equal(c.want) {
+			t.Errorf("box %s = %s, want %s", c.box, c.got.String(), c.want.String())
+		}
+	}
+}